@@ -0,0 +1,263 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// protoc-gen-protodoc is a protoc plugin that generates the same
+// documentation as the protodoc command, e.g.:
+//
+//	protoc --protodoc_out=docs --protodoc_opt=root_msg=acme.Config,format=textpb config.proto
+//
+// Supported parameters (comma separated):
+//
+//	root_msg=<msg>     Root message to start documentation from, can be
+//	                   repeated. Multiple messages can also be separated by
+//	                   ':'.
+//	auto_roots=true    Use messages that are not referenced by any other
+//	                   message in the files to generate as root messages.
+//	output=<output>    Output type: html (default), markdown or json.
+//	format=<format>    Config syntax: yaml (default), textpb or json. For html
+//	                   output, multiple formats separated by ':' (e.g.
+//	                   yaml:textpb) are shown as switchable tabs.
+//	json_names=true    Use JSON names for YAML output.
+//	site_generator=<g> Generate pages for a static site generator: hugo,
+//	                   mkdocs or docusaurus.
+//	title=<title>      Title of the documentation section, for the static
+//	                   site generators.
+//	nav_prefix=<path>  Path of the output directory relative to the MkDocs
+//	                   docs_dir, prefixed to the paths in mkdocs-nav.yml.
+//	json_schema=true   Generate JSON schema for each root message.
+//	search_index=true  Generate a search index and add a search box to the
+//	                   HTML pages.
+//	site=true          Generate a standalone site with navigation, instead of
+//	                   HTML fragments.
+//	extra_msgs=<msg>   Extra message to include, can be repeated. Multiple
+//	                   messages can also be separated by ':'.
+//	services=<svc>     gRPC services (or package prefixes ending with ".*")
+//	                   to document, can be repeated.
+//	group_by=<by>      How to group messages into pages: prefix, package or
+//	                   file. Default is prefix.
+//	group_root=<pkg>   Root package for the prefix grouping.
+//	group_depth=<n>    Package components after group_root for the prefix
+//	                   grouping.
+//	external_link=<pattern>=<url_template>
+//	                   Link types documented elsewhere, can be repeated.
+//	exclude_msgs=<msg> Messages (or package prefixes ending with ".*") to not
+//	                   document, can be repeated.
+//	home_url=<url>     Home URL for the documentation.
+//	template_dir=<dir> Directory with the templates overriding the built-in
+//	                   HTML templates.
+//	config=<file>      YAML config file, see protodoc.Config. Other parameters
+//	                   override the config file.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"github.com/manugarg/protodoc/internal/protodoc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// parseParams parses the plugin parameters into a protodoc config. Inputs
+// config is not used, protos come from the CodeGeneratorRequest.
+func parseParams(s string) (*protodoc.Config, error) {
+	cfg := &protodoc.Config{}
+
+	// Load the config file first, so that other parameters can override it.
+	var kvs [][2]string
+	for _, kv := range strings.Split(s, ",") {
+		if kv == "" {
+			continue
+		}
+		key, val, _ := strings.Cut(kv, "=")
+		if key == "config" {
+			var err error
+			if cfg, err = protodoc.LoadConfig(val); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		kvs = append(kvs, [2]string{key, val})
+	}
+
+	for _, kv := range kvs {
+		key, val := kv[0], kv[1]
+		switch key {
+		case "root_msg":
+			cfg.Roots = append(cfg.Roots, splitMsgs(val)...)
+		case "auto_roots", "json_names", "json_schema", "search_index", "site":
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value %s: %v", key, val, err)
+			}
+			switch key {
+			case "auto_roots":
+				cfg.AutoRoots = b
+			case "json_names":
+				cfg.JSONNames = b
+			case "json_schema":
+				cfg.JSONSchema = b
+			case "search_index":
+				cfg.SearchIndex = b
+			case "site":
+				cfg.Site = b
+			}
+		case "output":
+			cfg.Output = val
+		case "format":
+			cfg.Format = strings.Join(splitMsgs(val), ",")
+		case "home_url":
+			cfg.HomeURL = val
+		case "site_generator":
+			cfg.SiteGenerator = val
+		case "title":
+			cfg.Title = val
+		case "nav_prefix":
+			cfg.NavPrefix = val
+		case "template_dir":
+			cfg.TemplateDir = val
+		case "extra_msgs":
+			cfg.ExtraMsgs = append(cfg.ExtraMsgs, splitMsgs(val)...)
+		case "exclude_msgs":
+			cfg.ExcludeMsgs = append(cfg.ExcludeMsgs, splitMsgs(val)...)
+		case "services":
+			cfg.Services = append(cfg.Services, splitMsgs(val)...)
+		case "group_by":
+			cfg.Grouping.By = val
+		case "group_root":
+			cfg.Grouping.Root = val
+		case "group_depth":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid group_depth value %s: %v", val, err)
+			}
+			cfg.Grouping.Depth = n
+		case "external_link":
+			pattern, url, _ := strings.Cut(val, "=")
+			cfg.ExternalLinks = append(cfg.ExternalLinks, protodoc.ExternalLinkConfig{Pattern: pattern, URL: url})
+		default:
+			return nil, fmt.Errorf("unknown parameter: %s", key)
+		}
+	}
+
+	cfg.Inputs = protodoc.InputsConfig{}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func splitMsgs(s string) []string {
+	var msgs []string
+	for _, msg := range strings.Split(s, ":") {
+		if msg != "" {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func generate(req *pluginpb.CodeGeneratorRequest, l *logger.Logger) (*pluginpb.CodeGeneratorResponse, error) {
+	cfg, err := parseParams(req.GetParameter())
+	if err != nil {
+		return nil, err
+	}
+
+	fds := &descriptorpb.FileDescriptorSet{File: req.GetProtoFile()}
+	if err := protodoc.RegisterFileDescriptorSet(protodoc.Files, fds, l); err != nil {
+		return nil, err
+	}
+
+	f := cfg.Formatter()
+	rootMsgs := cfg.RootMsgs()
+	if cfg.AutoRoots {
+		var fds []protoreflect.FileDescriptor
+		for _, name := range req.GetFileToGenerate() {
+			fd, err := protodoc.Files.FindFileByPath(name)
+			if err != nil {
+				return nil, fmt.Errorf("error finding file %s: %v", name, err)
+			}
+			fds = append(fds, fd)
+		}
+		rootMsgs = protodoc.FindRootMessages(fds, f)
+	}
+
+	roots := protodoc.NewRoots(rootMsgs)
+	pages, err := protodoc.GenerateDocsInFormats(roots, cfg.ExtraMsgNames(), cfg.Formatters(), l)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := cfg.Renderer().Render(pages)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.JSONSchema {
+		for _, root := range roots {
+			b, err := protodoc.GenerateJSONSchema(root.Msg, f)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, &protodoc.File{Path: protodoc.JSONSchemaPath(root), Content: b})
+		}
+	}
+
+	// Proto3 optional fields are supported, as synthetic oneofs are ignored
+	// in the documentation. protoc and buf refuse to run the plugin on the
+	// files with them otherwise.
+	resp := &pluginpb.CodeGeneratorResponse{
+		SupportedFeatures: proto.Uint64(uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)),
+	}
+	for _, file := range files {
+		resp.File = append(resp.File, &pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String(file.Path),
+			Content: proto.String(string(file.Content)),
+		})
+	}
+	return resp, nil
+}
+
+func main() {
+	l := &logger.Logger{}
+
+	in, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		l.Criticalf("Error reading request: %v", err)
+	}
+
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(in, req); err != nil {
+		l.Criticalf("Error parsing request: %v", err)
+	}
+
+	resp, err := generate(req, l)
+	if err != nil {
+		resp = &pluginpb.CodeGeneratorResponse{Error: proto.String(err.Error())}
+	}
+
+	out, err := proto.Marshal(resp)
+	if err != nil {
+		l.Criticalf("Error marshaling response: %v", err)
+	}
+	if _, err := os.Stdout.Write(out); err != nil {
+		l.Criticalf("Error writing response: %v", err)
+	}
+}
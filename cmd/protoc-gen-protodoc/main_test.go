@@ -0,0 +1,177 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/manugarg/protodoc/internal/protodoc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestParseParams(t *testing.T) {
+	cfg, err := parseParams("root_msg=acme.A:acme.B,root_msg=acme.C,output=markdown,format=textpb," +
+		"json_names=true,json_schema=true,home_url=/docs,title=Config," +
+		"extra_msgs=acme.X:acme.Y,exclude_msgs=acme.internal.*,services=acme.api.*:acme.admin.Admin," +
+		"group_by=package,group_root=acme,group_depth=2,external_link=google.protobuf.*=https://example.com/{{.Name}}")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"acme.A", "acme.B", "acme.C"}, cfg.Roots)
+	assert.Equal(t, protodoc.OutputMarkdown, cfg.Output)
+	assert.Equal(t, "textpb", cfg.Format)
+	assert.True(t, cfg.JSONNames)
+	assert.True(t, cfg.JSONSchema)
+	assert.Equal(t, "/docs", cfg.HomeURL)
+	assert.Equal(t, "Config", cfg.Title)
+	assert.Equal(t, []string{"acme.X", "acme.Y"}, cfg.ExtraMsgs)
+	assert.Equal(t, []string{"acme.internal.*"}, cfg.ExcludeMsgs)
+	assert.Equal(t, []string{"acme.api.*", "acme.admin.Admin"}, cfg.Services)
+	assert.Equal(t, protodoc.GroupingConfig{By: "package", Root: "acme", Depth: 2}, cfg.Grouping)
+	assert.Equal(t, []protodoc.ExternalLinkConfig{{Pattern: "google.protobuf.*", URL: "https://example.com/{{.Name}}"}}, cfg.ExternalLinks)
+
+	// Multiple formats, separated by ':', search index and site.
+	tmplDir := t.TempDir()
+	cfg, err = parseParams("auto_roots=true,format=yaml:textpb,search_index=true,site=true,template_dir=" + tmplDir)
+	assert.NoError(t, err)
+	assert.Equal(t, tmplDir, cfg.TemplateDir)
+	assert.True(t, cfg.AutoRoots)
+	assert.Equal(t, "yaml,textpb", cfg.Format)
+	assert.True(t, cfg.SearchIndex)
+	assert.True(t, cfg.Site)
+
+	cfg, err = parseParams("root_msg=acme.A,site_generator=hugo")
+	assert.NoError(t, err)
+	assert.Equal(t, "hugo", cfg.SiteGenerator)
+
+	cfg, err = parseParams("root_msg=acme.A,site_generator=mkdocs,nav_prefix=reference/config")
+	assert.NoError(t, err)
+	assert.Equal(t, "reference/config", cfg.NavPrefix)
+
+	// Config file is loaded first, other parameters override it.
+	configFile := filepath.Join(t.TempDir(), "protodoc.yaml")
+	assert.NoError(t, os.WriteFile(configFile, []byte("roots: [acme.A]\nformat: json\ntitle: File\ninputs:\n  proto_root_dir: protos\n"), 0644))
+	cfg, err = parseParams("title=Param,config=" + configFile)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"acme.A"}, cfg.Roots)
+	assert.Equal(t, "json", cfg.Format)
+	assert.Equal(t, "Param", cfg.Title)
+	assert.Equal(t, protodoc.InputsConfig{ProtoRootDir: "."}, cfg.Inputs, "inputs come from the request")
+
+	for _, params := range []string{
+		"unknown=1",
+		"auto_roots=maybe",
+		"json_names=2",
+		"json_schema=x",
+		"search_index=yes please",
+		"site=on",
+		"group_depth=two",
+		"output=pdf",
+		"format=yaml:xml",
+		"group_by=size",
+		"root_msg=not a message",
+		"auto_roots=true,root_msg=acme.A",
+		"config=" + filepath.Join(t.TempDir(), "missing.yaml"),
+	} {
+		t.Run(params, func(t *testing.T) {
+			_, err := parseParams(params)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// addFileWithDeps adds the file, after its dependencies, to the request.
+func addFileWithDeps(req *pluginpb.CodeGeneratorRequest, fd protoreflect.FileDescriptor, seen map[string]bool) {
+	if seen[fd.Path()] {
+		return
+	}
+	seen[fd.Path()] = true
+	for i := 0; i < fd.Imports().Len(); i++ {
+		addFileWithDeps(req, fd.Imports().Get(i).FileDescriptor, seen)
+	}
+	req.ProtoFile = append(req.ProtoFile, protodesc.ToFileDescriptorProto(fd))
+}
+
+func TestGenerate(t *testing.T) {
+	// Parse the testdata protos by their import names, as protoc would.
+	const testdata, pkgPrefix = "../../internal/protodoc/testdata", "github.com/manugarg/protodoc/"
+	var names []string
+	assert.NoError(t, filepath.WalkDir(testdata, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && filepath.Ext(path) == ".proto" {
+			rel, _ := filepath.Rel(testdata, path)
+			names = append(names, pkgPrefix+filepath.ToSlash(rel))
+		}
+		return err
+	}))
+	p := protoparse.Parser{
+		Accessor: func(name string) (io.ReadCloser, error) {
+			return os.Open(filepath.Join(testdata, strings.TrimPrefix(name, pkgPrefix)))
+		},
+		LookupImportProto: func(name string) (*descriptorpb.FileDescriptorProto, error) {
+			fd, err := protoregistry.GlobalFiles.FindFileByPath(name)
+			if err != nil {
+				return nil, err
+			}
+			return protodesc.ToFileDescriptorProto(fd), nil
+		},
+		IncludeSourceCodeInfo: true,
+	}
+	fds, err := p.ParseFiles(names...)
+	assert.NoError(t, err)
+
+	req := &pluginpb.CodeGeneratorRequest{
+		Parameter:      proto.String("root_msg=acme.config.ServerConfig,group_root=acme,output=markdown,json_schema=true"),
+		FileToGenerate: []string{pkgPrefix + "acme/proto/config.proto"},
+	}
+	seen := map[string]bool{}
+	for _, fd := range fds {
+		addFileWithDeps(req, fd.UnwrapFile(), seen)
+	}
+
+	// Request goes through the wire, as it does from protoc.
+	b, err := proto.Marshal(req)
+	assert.NoError(t, err)
+	req = &pluginpb.CodeGeneratorRequest{}
+	assert.NoError(t, proto.Unmarshal(b, req))
+
+	resp, err := generate(req, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, resp.GetError())
+	assert.Equal(t, uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL), resp.GetSupportedFeatures())
+
+	contents := map[string]string{}
+	for _, f := range resp.GetFile() {
+		contents[f.GetName()] = f.GetContent()
+	}
+	assert.Contains(t, contents, "overview.md")
+	assert.Contains(t, contents, "config.md")
+	assert.Contains(t, contents["overview.md"], "listen_addr: <string> | default: :8080")
+	assert.Contains(t, contents, "overview.schema.json")
+
+	// Unknown messages are reported as errors.
+	req.Parameter = proto.String("root_msg=acme.config.Unknown")
+	_, err = generate(req, nil)
+	assert.Error(t, err)
+}
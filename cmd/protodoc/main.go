@@ -17,13 +17,10 @@ package main
 import (
 	"flag"
 	"fmt"
-	"html/template"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 
-	"github.com/Masterminds/sprig/v3"
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/manugarg/protodoc/internal/protodoc"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -31,124 +28,275 @@ import (
 
 var (
 	versionFlag   = flag.Bool("version", false, "Print version and exit")
-	outFmt        = flag.String("format", "yaml", "textpb or yaml")
+	configFile    = flag.String("config", "", "YAML config file. Flags explicitly set on the command line override the config file.")
+	output        = flag.String("output", "html", "Output type: html, markdown or json (machine-readable model of the documentation).")
+	outFmt        = flag.String("format", "yaml", "textpb, yaml or json. For html output, can be a comma separated list (e.g. yaml,textpb) to show the config in each syntax as switchable tabs.")
 	jsonNames     = flag.Bool("json_names", false, "Use JSON names for YAML output.")
+	jsonSchema    = flag.Bool("json_schema", false, "Generate JSON schema for each root message, for YAML/JSON configs.")
 	outDir        = flag.String("out_dir", "proto_docs", "Output directory for the documentation.")
 	protoRootDir  = flag.String("proto_root_dir", ".", "Root directory for the proto files.")
 	packagePrefix = flag.String("package_prefix", "", "Package prefix to resolve import paths")
-	rootMsg       = flag.String("root_msg", "cloudprober.ProberConfig", "Root message to start documentation from.")
+	descriptorSet = flag.String("descriptor_set", "", "FileDescriptorSet file (binary, or JSON if it has .json extension) to load protos from, instead of parsing the proto files under proto_root_dir.")
+	rootMsg       = flag.String("root_msg", "cloudprober.ProberConfig", "Root messages to start documentation from. Comma separated list. Each root message gets its own overview page.")
+	autoRoots     = flag.Bool("auto_roots", false, "Use messages that are not referenced by any other message as root messages, instead of root_msg.")
 	extraMsgs     = flag.String("extra_msgs", "", "Extra messages to include in the documentation. Comma separated list.")
+	services      = flag.String("services", "", "gRPC services to document, along with their request and response messages. Comma separated list of full names or package prefixes ending with '.*', e.g. 'acme.api.*'.")
+	groupBy       = flag.String("group_by", "prefix", "How to group messages into pages: prefix (first group_depth package components after group_root), package (full proto package) or file (proto file).")
+	groupRoot     = flag.String("group_root", protodoc.DefaultGrouping.Root, "Root package for the prefix grouping.")
+	groupDepth    = flag.Int("group_depth", protodoc.DefaultGrouping.Depth, "Number of package components after group_root to use for the prefix grouping.")
+	homeURL       = flag.String("home_url", "", "Home URL for the documentation.")
+	searchIndex   = flag.Bool("search_index", false, "Generate a search index (search-index.json) of all messages, fields and enum values, and add a search box to the HTML pages.")
+	site          = flag.Bool("site", false, "Generate a standalone site: complete HTML documents with a navigation sidebar, breadcrumbs, table of contents and shared static assets.")
+	siteGenerator = flag.String("site_generator", "", "Generate pages for a static site generator: hugo, mkdocs or docusaurus. Pages get the front matter and the section files (_index.md, mkdocs-nav.yml or _category_.json) expected by the site generator.")
+	title         = flag.String("title", protodoc.DefaultSiteTitle, "Title of the documentation section, for the static site generators.")
+	navPrefix     = flag.String("nav_prefix", "", "Path of out_dir relative to the MkDocs docs_dir, prefixed to the paths in mkdocs-nav.yml.")
+	templateDir   = flag.String("template_dir", "", "Directory with the templates (page.tmpl, style.tmpl, message_header.tmpl, token.tmpl) overriding the built-in HTML templates.")
 )
 
-// These variables get overwritten by using -ldflags="-X main.<var>=<value?" at
-// the build time.
-var version string
+var externalLinks externalLinksFlag
 
-type msgTokens struct {
-	Name   string
-	Tokens []*protodoc.Token
+func init() {
+	flag.Var(&externalLinks, "external_link", "Link types documented elsewhere, specified as <pattern>=<url_template>, e.g. 'google.protobuf.*=https://protobuf.dev/reference/protobuf/google.protobuf/#{{.Name}}'. Can be repeated.")
 }
 
-var docTmpl = template.Must(template.New("index").Funcs(sprig.TxtFuncMap()).Parse(protodoc.DocTmpl))
+// externalLinksFlag implements flag.Value for the repeated external_link flag.
+type externalLinksFlag []protodoc.ExternalLinkConfig
 
-func writeDoc(pkg string, mTokens []*msgTokens, l *logger.Logger) {
-	if pkg == "index" {
-		pkg = "overview"
+func (el *externalLinksFlag) String() string {
+	var patterns []string
+	for _, l := range *el {
+		patterns = append(patterns, l.Pattern)
+	}
+	return strings.Join(patterns, ",")
+}
+
+func (el *externalLinksFlag) Set(s string) error {
+	l, err := protodoc.ParseExternalLink(s)
+	if err != nil {
+		return err
 	}
+	_, url, _ := strings.Cut(s, "=")
+	*el = append(*el, protodoc.ExternalLinkConfig{Pattern: l.Pattern, URL: strings.TrimSpace(url)})
+	return nil
+}
 
-	pkgDir := filepath.Join(*outDir, pkg)
-	if err := os.MkdirAll(pkgDir, 0755); err != nil {
+// These variables get overwritten by using -ldflags="-X main.<var>=<value?" at
+// the build time.
+var version string
+
+func writeFile(outDir string, file *protodoc.File, l *logger.Logger) {
+	outFile := filepath.Join(outDir, file.Path)
+	if err := os.MkdirAll(filepath.Dir(outFile), 0755); err != nil {
 		if !os.IsExist(err) {
 			panic(err)
 		}
 	}
 
-	outF, err := os.OpenFile(filepath.Join(pkgDir, "index.html"), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
-		l.Criticalf("Error opening output file: %v", err)
+	if err := os.WriteFile(outFile, file.Content, 0644); err != nil {
+		l.Criticalf("Error writing output file: %v", err)
 	}
-	defer outF.Close()
+}
 
-	if err := docTmpl.Execute(outF, mTokens); err != nil {
-		l.Criticalf("Error executing template: %v", err)
+func splitMsgs(s string) []string {
+	var msgs []string
+	for _, msg := range strings.Split(s, ",") {
+		if msg == "" {
+			continue
+		}
+		msgs = append(msgs, msg)
 	}
+	return msgs
 }
 
-func packagesDocs(msgs []protoreflect.FullName, f protodoc.Formatter, l *logger.Logger) {
-	f = f.WithDepth(1)
-	msgToDoc := map[string][]*protodoc.Token{}
+// applyFlags overrides the config with the flags explicitly set on the
+// command line.
+func applyFlags(cfg *protodoc.Config) {
+	set := map[string]bool{}
+	flag.Visit(func(fl *flag.Flag) {
+		set[fl.Name] = true
+		switch fl.Name {
+		case "output":
+			cfg.Output = *output
+		case "format":
+			cfg.Format = *outFmt
+		case "json_names":
+			cfg.JSONNames = *jsonNames
+		case "json_schema":
+			cfg.JSONSchema = *jsonSchema
+		case "out_dir":
+			cfg.OutDir = *outDir
+		case "proto_root_dir":
+			cfg.Inputs.ProtoRootDir = *protoRootDir
+		case "package_prefix":
+			cfg.Inputs.PackagePrefix = *packagePrefix
+		case "descriptor_set":
+			cfg.Inputs.DescriptorSet = *descriptorSet
+		case "root_msg":
+			cfg.Roots = splitMsgs(*rootMsg)
+		case "auto_roots":
+			cfg.AutoRoots = *autoRoots
+		case "extra_msgs":
+			cfg.ExtraMsgs = splitMsgs(*extraMsgs)
+		case "services":
+			cfg.Services = splitMsgs(*services)
+		case "group_by":
+			cfg.Grouping.By = *groupBy
+		case "group_root":
+			cfg.Grouping.Root = *groupRoot
+		case "group_depth":
+			cfg.Grouping.Depth = *groupDepth
+		case "home_url":
+			cfg.HomeURL = *homeURL
+		case "template_dir":
+			cfg.TemplateDir = *templateDir
+		case "search_index":
+			cfg.SearchIndex = *searchIndex
+		case "site":
+			cfg.Site = *site
+		case "site_generator":
+			cfg.SiteGenerator = *siteGenerator
+		case "title":
+			cfg.Title = *title
+		case "nav_prefix":
+			cfg.NavPrefix = *navPrefix
+		case "external_link":
+			cfg.ExternalLinks = append(cfg.ExternalLinks, externalLinks...)
+		}
+	})
 
-	for len(msgs) > 0 {
-		var nextLoop []protoreflect.FullName
-		for _, msgName := range msgs {
-			m, err := protodoc.Files.FindDescriptorByName(protoreflect.FullName(msgName))
-			if err != nil {
-				panic(err)
-			}
+	// Inputs and roots on the command line replace their alternatives from
+	// the config file. Descriptor set takes precedence over proto_root_dir.
+	if set["descriptor_set"] {
+		cfg.Inputs.ProtoRootDir = ""
+	} else if set["proto_root_dir"] {
+		cfg.Inputs.DescriptorSet = ""
+	}
+	if set["auto_roots"] && *autoRoots {
+		cfg.Roots = nil
+	} else if set["root_msg"] {
+		cfg.AutoRoots = false
+	}
+}
 
-			toks, next := protodoc.DumpMessage(m.(protoreflect.MessageDescriptor), f)
-			msgToDoc[string(msgName)] = toks
-			nextLoop = append(nextLoop, next...)
+// loadProtos loads the proto descriptors into protodoc.Files, as per the
+// config.
+func loadProtos(cfg *protodoc.Config, l *logger.Logger) {
+	if cfg.Inputs.DescriptorSet != "" {
+		if err := protodoc.LoadFileDescriptorSet(protodoc.Files, cfg.Inputs.DescriptorSet, l); err != nil {
+			l.Criticalf("Error loading descriptor set: %v", err)
 		}
-		msgs = nextLoop
+	} else {
+		protodoc.BuildFileDescRegistry(protodoc.Files, cfg.Inputs.ProtoRootDir, cfg.Inputs.PackagePrefix, l)
 	}
+}
 
-	var msgNames []string
-	for key := range msgToDoc {
-		msgNames = append(msgNames, key)
+// rootFiles returns the files to look for the root messages in. Descriptor
+// sets also include the dependencies of the documented files (protoc
+// --include_imports), e.g. google/api/http.proto, that are skipped. Files
+// parsed from the proto root are all documented.
+func rootFiles(cfg *protodoc.Config) []protoreflect.FileDescriptor {
+	if cfg.Inputs.DescriptorSet != "" {
+		return protodoc.TopLevelFiles(protodoc.Files)
 	}
 
-	packages := protodoc.ArrangeIntoPackages(msgNames, l)
+	var fds []protoreflect.FileDescriptor
+	protodoc.Files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		fds = append(fds, fd)
+		return true
+	})
+	return fds
+}
 
-	for pkg, msgs := range packages {
-		sort.Strings(msgs)
-		mtoks := []*msgTokens{}
-		for _, msg := range msgs {
-			mtoks = append(mtoks, &msgTokens{Name: msg, Tokens: protodoc.ProcessTokensForHTML(msgToDoc[msg], f)})
-		}
-		writeDoc(pkg, mtoks, l)
+// sample writes a sample config for the root message to stdout, in the
+// first of the configured formats.
+func sample(cfg *protodoc.Config, l *logger.Logger) {
+	if cfg.AutoRoots || len(cfg.Roots) != 1 {
+		l.Criticalf("sample: exactly one root message should be specified using --root_msg")
 	}
+
+	loadProtos(cfg, l)
+
+	b, err := protodoc.GenerateSample(cfg.RootMsgs()[0], cfg.Formatter())
+	if err != nil {
+		l.Criticalf("Error generating sample config: %v", err)
+	}
+	os.Stdout.Write(b)
 }
 
 func main() {
-	flag.Parse()
+	// "protodoc sample [flags]" generates a sample config instead of the
+	// documentation.
+	sampleCmd := len(os.Args) > 1 && os.Args[1] == "sample"
+	if sampleCmd {
+		flag.CommandLine.Parse(os.Args[2:])
+	} else {
+		flag.Parse()
+	}
 
 	if *versionFlag {
 		fmt.Println(version)
 		return
 	}
 
-	if err := os.MkdirAll(*outDir, 0755); err != nil {
+	l := &logger.Logger{}
+
+	cfg := &protodoc.Config{}
+	if *configFile != "" {
+		var err error
+		if cfg, err = protodoc.LoadConfig(*configFile); err != nil {
+			l.Criticalf("%v", err)
+		}
+	}
+	applyFlags(cfg)
+	if err := cfg.Validate(); err != nil {
+		l.Criticalf("%v", err)
+	}
+
+	if sampleCmd {
+		sample(cfg, l)
+		return
+	}
+
+	if err := os.MkdirAll(cfg.OutDir, 0755); err != nil {
 		if !os.IsExist(err) {
 			panic(err)
 		}
 	}
 
-	l := &logger.Logger{}
+	loadProtos(cfg, l)
 
-	protodoc.BuildFileDescRegistry(protodoc.Files, *protoRootDir, *packagePrefix, l)
+	f := cfg.Formatter()
 
-	// Top level message
-	m, err := protodoc.Files.FindDescriptorByName(protoreflect.FullName(*rootMsg))
-	if err != nil {
-		panic(err)
+	rootMsgs := cfg.RootMsgs()
+	if cfg.AutoRoots {
+		rootMsgs = protodoc.FindRootMessages(rootFiles(cfg), f)
+		l.Infof("Found root messages: %v", rootMsgs)
 	}
 
-	f := protodoc.Formatter{}.WithYAML(*outFmt == "yaml", *jsonNames).WithRelPath("..")
-
-	toks, nextMessageNames := protodoc.DumpMessage(m.(protoreflect.MessageDescriptor), f.WithDepth(2))
+	roots := protodoc.NewRoots(rootMsgs)
+	pages, err := protodoc.GenerateDocsInFormats(roots, cfg.ExtraMsgNames(), cfg.Formatters(), l)
+	if err != nil {
+		l.Criticalf("Error generating documentation: %v", err)
+	}
 
-	mTokens := &msgTokens{Name: "", Tokens: protodoc.ProcessTokensForHTML(toks, f)}
-	writeDoc("index", []*msgTokens{mTokens}, l)
+	files, err := cfg.Renderer().Render(pages)
+	if err != nil {
+		l.Criticalf("Error rendering documentation: %v", err)
+	}
 
-	// Package level documentation
-	for _, msg := range strings.Split(*extraMsgs, ",") {
-		if msg == "" {
-			continue
+	if cfg.JSONSchema {
+		for _, root := range roots {
+			b, err := protodoc.GenerateJSONSchema(root.Msg, f)
+			if err != nil {
+				l.Criticalf("Error generating JSON schema: %v", err)
+			}
+			files = append(files, &protodoc.File{Path: protodoc.JSONSchemaPath(root), Content: b})
 		}
-		nextMessageNames = append(nextMessageNames, protoreflect.FullName(msg))
 	}
-	packagesDocs(nextMessageNames, f, l)
 
-	l.Infof("Documentation generated in %s", *outDir)
+	for _, file := range files {
+		writeFile(cfg.OutDir, file, l)
+	}
+
+	l.Infof("Documentation generated in %s", cfg.OutDir)
 }
@@ -15,6 +15,7 @@
 package protodoc
 
 import (
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
@@ -23,7 +24,12 @@ import (
 
 	"github.com/cloudprober/cloudprober/logger"
 	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 func BuildFileDescRegistry(files *protoregistry.Files, protoRoot, pkgPrefix string, l *logger.Logger) {
@@ -38,6 +44,15 @@ func BuildFileDescRegistry(files *protoregistry.Files, protoRoot, pkgPrefix stri
 			// Top files
 			return os.Open(name)
 		},
+		// Imports not found in the proto root, e.g. google/api/annotations.proto,
+		// are looked up in the descriptors compiled into protodoc.
+		LookupImportProto: func(name string) (*descriptorpb.FileDescriptorProto, error) {
+			fd, err := protoregistry.GlobalFiles.FindFileByPath(name)
+			if err != nil {
+				return nil, err
+			}
+			return protodesc.ToFileDescriptorProto(fd), nil
+		},
 		IncludeSourceCodeInfo: true,
 	}
 
@@ -65,3 +80,74 @@ func BuildFileDescRegistry(files *protoregistry.Files, protoRoot, pkgPrefix stri
 		return nil
 	})
 }
+
+// LoadFileDescriptorSet reads a FileDescriptorSet from the given file and
+// registers all the files in it with the provided registry. The file can be
+// in the binary format (e.g. output of "protoc -o" or "buf build -o") or in the
+// JSON format, detected by the ".json" extension. Descriptor set should be
+// self-contained (protoc --include_imports), and to get comments in the
+// documentation, it should be generated with source info included
+// (protoc --include_source_info).
+func LoadFileDescriptorSet(files *protoregistry.Files, fdsFile string, l *logger.Logger) error {
+	b, err := os.ReadFile(fdsFile)
+	if err != nil {
+		return fmt.Errorf("error reading descriptor set %s: %v", fdsFile, err)
+	}
+
+	fds := &descriptorpb.FileDescriptorSet{}
+	if filepath.Ext(fdsFile) == ".json" {
+		err = protojson.Unmarshal(b, fds)
+	} else {
+		err = proto.Unmarshal(b, fds)
+	}
+	if err != nil {
+		return fmt.Errorf("error parsing descriptor set %s: %v", fdsFile, err)
+	}
+
+	return RegisterFileDescriptorSet(files, fds, l)
+}
+
+// RegisterFileDescriptorSet builds file descriptors from the given descriptor
+// set and registers them with the provided registry. Files already present in
+// the registry are skipped.
+func RegisterFileDescriptorSet(files *protoregistry.Files, fds *descriptorpb.FileDescriptorSet, l *logger.Logger) error {
+	newFiles, err := protodesc.NewFiles(fds)
+	if err != nil {
+		return fmt.Errorf("error building file descriptors: %v", err)
+	}
+
+	newFiles.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		if _, e := files.FindFileByPath(fd.Path()); e == nil {
+			l.Debugf("File %s already registered, skipping", fd.Path())
+			return true
+		}
+		if err = files.RegisterFile(fd); err != nil {
+			err = fmt.Errorf("error registering file %s: %v", fd.Path(), err)
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// TopLevelFiles returns the files in the registry that are not imported by
+// any other file in it, i.e. the files a self-contained descriptor set was
+// built for, without the dependencies added by protoc --include_imports.
+func TopLevelFiles(files *protoregistry.Files) []protoreflect.FileDescriptor {
+	imported := map[string]bool{}
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		for i := 0; i < fd.Imports().Len(); i++ {
+			imported[fd.Imports().Get(i).Path()] = true
+		}
+		return true
+	})
+
+	var fds []protoreflect.FileDescriptor
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		if !imported[fd.Path()] {
+			fds = append(fds, fd)
+		}
+		return true
+	})
+	return fds
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestLoadFileDescriptorSet(t *testing.T) {
+	d, err := Files.FindDescriptorByName("cloudprober.probes.ProbeDef")
+	assert.NoError(t, err)
+
+	// Build a self-contained descriptor set: imports first, then the file.
+	fds := &descriptorpb.FileDescriptorSet{}
+	fd := d.ParentFile()
+	for i := 0; i < fd.Imports().Len(); i++ {
+		fds.File = append(fds.File, protodesc.ToFileDescriptorProto(fd.Imports().Get(i).FileDescriptor))
+	}
+	fds.File = append(fds.File, protodesc.ToFileDescriptorProto(fd))
+
+	tmpDir := t.TempDir()
+	binB, err := proto.Marshal(fds)
+	assert.NoError(t, err)
+	jsonB, err := protojson.Marshal(fds)
+	assert.NoError(t, err)
+
+	for _, name := range []string{"fds.pb", "fds.json"} {
+		t.Run(name, func(t *testing.T) {
+			b := binB
+			if filepath.Ext(name) == ".json" {
+				b = jsonB
+			}
+			fdsFile := filepath.Join(tmpDir, name)
+			assert.NoError(t, os.WriteFile(fdsFile, b, 0644))
+
+			files := &protoregistry.Files{}
+			assert.NoError(t, LoadFileDescriptorSet(files, fdsFile, nil))
+			assert.Equal(t, len(fds.File), files.NumFiles())
+
+			d, err := files.FindDescriptorByName("cloudprober.probes.ProbeDef.interval_msec")
+			if !assert.NoError(t, err) {
+				return
+			}
+			loc := d.ParentFile().SourceLocations().ByDescriptor(d)
+			assert.Contains(t, loc.LeadingComments, "Interval between two probe runs")
+
+			// Loading again should be a no-op.
+			assert.NoError(t, LoadFileDescriptorSet(files, fdsFile, nil))
+		})
+	}
+
+	assert.Error(t, LoadFileDescriptorSet(&protoregistry.Files{}, filepath.Join(tmpDir, "missing.pb"), nil))
+}
+
+func TestTopLevelFiles(t *testing.T) {
+	d, err := Files.FindDescriptorByName("acme.api.ServerService")
+	assert.NoError(t, err)
+
+	// Descriptor set built with protoc --include_imports: dependencies first.
+	fds := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]bool{}
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		for i := 0; i < fd.Imports().Len(); i++ {
+			add(fd.Imports().Get(i).FileDescriptor)
+		}
+		fds.File = append(fds.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	add(d.ParentFile())
+
+	files := &protoregistry.Files{}
+	assert.NoError(t, RegisterFileDescriptorSet(files, fds, nil))
+	assert.Contains(t, seen, "google/api/http.proto")
+
+	topFiles := TopLevelFiles(files)
+	if assert.Len(t, topFiles, 1) {
+		assert.Equal(t, d.ParentFile().Path(), topFiles[0].Path())
+	}
+
+	// Messages of the dependencies are not roots.
+	oldFiles := Files
+	Files = files
+	defer func() { Files = oldFiles }()
+	var allFiles []protoreflect.FileDescriptor
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		allFiles = append(allFiles, fd)
+		return true
+	})
+	assert.Contains(t, FindRootMessages(allFiles, Formatter{}), protoreflect.FullName("google.api.Http"))
+	// acme.api only has the RPC messages, documented along with the service.
+	assert.Empty(t, FindRootMessages(topFiles, Formatter{}))
+}
@@ -0,0 +1,411 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the protodoc configuration. It's usually loaded from a YAML file,
+// e.g.:
+//
+//	inputs:
+//	  proto_root_dir: cloudprober
+//	  package_prefix: github.com/cloudprober/cloudprober
+//	roots:
+//	  - cloudprober.ProberConfig
+//	extra_msgs:
+//	  - cloudprober.rds.Resource
+//	services:
+//	  - cloudprober.rds.*
+//	output: html
+//	out_dir: docs
+//	format: yaml
+//	json_schema: true
+//	template_dir: templates
+//	search_index: true
+//	site_generator: hugo
+//	title: Configuration
+//	nav_prefix: reference/config
+//	grouping:
+//	  by: prefix
+//	  root: cloudprober
+//	  depth: 1
+//	external_links:
+//	  - pattern: google.protobuf.*
+//	    url: https://protobuf.dev/reference/protobuf/google.protobuf/#{{.Name}}
+//	exclude_msgs:
+//	  - cloudprober.internal.*
+type Config struct {
+	Inputs InputsConfig `yaml:"inputs"`
+
+	// Root messages, each documented on its own page. If AutoRoots is set,
+	// messages not referenced by any other message are used as roots.
+	Roots     []string `yaml:"roots"`
+	AutoRoots bool     `yaml:"auto_roots"`
+
+	// Extra messages to document, in addition to the ones reachable from the
+	// roots.
+	ExtraMsgs []string `yaml:"extra_msgs"`
+
+	// gRPC services to document, specified as full names or package
+	// prefixes ending with ".*". Services are documented on the package
+	// pages, along with their request and response messages.
+	Services []string `yaml:"services"`
+
+	// Output type (html, markdown or json), output directory and config
+	// syntax (yaml, textpb or json). For html output, format can be a comma
+	// separated list, e.g. "yaml,textpb", to show the config in each syntax
+	// as switchable tabs.
+	Output    string `yaml:"output"`
+	OutDir    string `yaml:"out_dir"`
+	Format    string `yaml:"format"`
+	JSONNames bool   `yaml:"json_names"`
+	HomeURL   string `yaml:"home_url"`
+
+	// Generate JSON schema for each root message.
+	JSONSchema bool `yaml:"json_schema"`
+
+	// Generate the search index, and add a search box to the HTML pages.
+	SearchIndex bool `yaml:"search_index"`
+
+	// Directory with the templates overriding the built-in HTML templates,
+	// see LoadTemplates. In the site mode, files in its "assets"
+	// subdirectory are added to the site's static assets.
+	TemplateDir string `yaml:"template_dir"`
+
+	// Generate a standalone site: complete HTML documents with navigation
+	// between them, instead of HTML fragments.
+	Site bool `yaml:"site"`
+
+	// Static site generator (hugo, mkdocs or docusaurus) to generate the
+	// pages for, and the title of the documentation section in it. Output
+	// defaults to the one used by the site generator.
+	SiteGenerator string `yaml:"site_generator"`
+	Title         string `yaml:"title"`
+
+	// Path of the output directory relative to the MkDocs docs_dir, e.g.
+	// "reference/config" for out_dir docs/reference/config. MkDocs resolves
+	// the nav paths relative to docs_dir.
+	NavPrefix string `yaml:"nav_prefix"`
+
+	Grouping      GroupingConfig       `yaml:"grouping"`
+	ExternalLinks []ExternalLinkConfig `yaml:"external_links"`
+
+	// Messages that should not be documented, specified as full names or
+	// package prefixes ending with ".*".
+	ExcludeMsgs []string `yaml:"exclude_msgs"`
+
+	externalLinks []*ExternalLink
+	grouping      Grouping
+	tmpl          *template.Template
+}
+
+// InputsConfig specifies where to load the proto descriptors from: either
+// proto files under ProtoRootDir, or a FileDescriptorSet file.
+type InputsConfig struct {
+	ProtoRootDir  string `yaml:"proto_root_dir"`
+	PackagePrefix string `yaml:"package_prefix"`
+	DescriptorSet string `yaml:"descriptor_set"`
+}
+
+// GroupingConfig is the configuration for grouping messages into pages.
+// See Grouping for details.
+type GroupingConfig struct {
+	By    string `yaml:"by"`
+	Root  string `yaml:"root"`
+	Depth int    `yaml:"depth"`
+}
+
+// ExternalLinkConfig is the configuration for a link to types documented
+// elsewhere. See ExternalLink for details.
+type ExternalLinkConfig struct {
+	Pattern string `yaml:"pattern"`
+	URL     string `yaml:"url"`
+}
+
+// LoadConfig loads the configuration from the given YAML file. Unknown
+// fields result in an error. Config should be validated using Validate
+// before use.
+func LoadConfig(configFile string) (*Config, error) {
+	b, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file %s: %v", configFile, err)
+	}
+
+	c := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing config file %s: %v", configFile, err)
+	}
+	return c, nil
+}
+
+func validatePattern(pattern string) error {
+	if pattern == "" || strings.Contains(strings.TrimSuffix(pattern, ".*"), "*") {
+		return fmt.Errorf("invalid pattern %q, should be a full name or a package prefix ending with \".*\"", pattern)
+	}
+	return nil
+}
+
+// Validate fills in the defaults for the unset fields and validates the
+// config.
+func (c *Config) Validate() error {
+	if c.Inputs.DescriptorSet != "" && c.Inputs.ProtoRootDir != "" {
+		return fmt.Errorf("config: only one of inputs.proto_root_dir and inputs.descriptor_set can be set")
+	}
+	if c.Inputs.DescriptorSet == "" && c.Inputs.ProtoRootDir == "" {
+		c.Inputs.ProtoRootDir = "."
+	}
+
+	if c.AutoRoots && len(c.Roots) != 0 {
+		return fmt.Errorf("config: only one of roots and auto_roots can be set")
+	}
+	if !c.AutoRoots && len(c.Roots) == 0 && len(c.Services) == 0 {
+		c.Roots = []string{"cloudprober.ProberConfig"}
+	}
+	for _, msg := range append(c.Roots, c.ExtraMsgs...) {
+		if !protoreflect.FullName(msg).IsValid() {
+			return fmt.Errorf("config: invalid message name: %q", msg)
+		}
+	}
+
+	if c.OutDir == "" {
+		c.OutDir = "proto_docs"
+	}
+
+	if c.SiteGenerator != "" {
+		output, err := SiteGeneratorOutput(c.SiteGenerator)
+		if err != nil {
+			return fmt.Errorf("config: site_generator: %v", err)
+		}
+		if c.Output != "" && c.Output != output {
+			return fmt.Errorf("config: site_generator %s requires %s output", c.SiteGenerator, output)
+		}
+		if c.Site {
+			return fmt.Errorf("config: only one of site and site_generator can be set")
+		}
+		c.Output = output
+	}
+	if c.NavPrefix != "" {
+		if c.SiteGenerator != SiteGeneratorMkDocs {
+			return fmt.Errorf("config: nav_prefix is supported only for the mkdocs site generator")
+		}
+		if path.IsAbs(c.NavPrefix) || path.Clean(c.NavPrefix) != c.NavPrefix || c.NavPrefix == ".." || strings.HasPrefix(c.NavPrefix, "../") {
+			return fmt.Errorf("config: nav_prefix should be a clean relative path, got %q", c.NavPrefix)
+		}
+	}
+
+	switch c.Output {
+	case "":
+		c.Output = OutputHTML
+	case OutputHTML, OutputMarkdown, OutputJSON:
+	default:
+		return fmt.Errorf("config: invalid output %q, should be html, markdown or json", c.Output)
+	}
+
+	if c.Format == "" {
+		c.Format = "yaml"
+	}
+	seen := map[string]bool{}
+	for _, format := range c.Formats() {
+		if format != "yaml" && format != "textpb" && format != "json" {
+			return fmt.Errorf("config: invalid format %q, should be yaml, textpb or json", format)
+		}
+		if seen[format] {
+			return fmt.Errorf("config: duplicate format %q", format)
+		}
+		seen[format] = true
+	}
+	if len(seen) > 1 && c.Output != OutputHTML {
+		return fmt.Errorf("config: multiple formats are supported only for html output")
+	}
+
+	c.grouping = DefaultGrouping
+	if c.Grouping.By != "" {
+		by, err := ParseGroupBy(c.Grouping.By)
+		if err != nil {
+			return fmt.Errorf("config: grouping: %v", err)
+		}
+		c.grouping.By = by
+	}
+	if c.Grouping.Root != "" {
+		c.grouping.Root = c.Grouping.Root
+	}
+	if c.Grouping.Depth < 0 {
+		return fmt.Errorf("config: grouping: invalid depth %d", c.Grouping.Depth)
+	}
+	if c.Grouping.Depth != 0 {
+		c.grouping.Depth = c.Grouping.Depth
+	}
+
+	c.externalLinks = nil
+	for _, elc := range c.ExternalLinks {
+		if err := validatePattern(elc.Pattern); err != nil {
+			return fmt.Errorf("config: external_links: %v", err)
+		}
+		el, err := NewExternalLink(elc.Pattern, elc.URL)
+		if err != nil {
+			return fmt.Errorf("config: external_links: %v", err)
+		}
+		c.externalLinks = append(c.externalLinks, el)
+	}
+
+	for _, pattern := range c.ExcludeMsgs {
+		if err := validatePattern(pattern); err != nil {
+			return fmt.Errorf("config: exclude_msgs: %v", err)
+		}
+	}
+
+	for _, pattern := range c.Services {
+		if err := validatePattern(pattern); err != nil {
+			return fmt.Errorf("config: services: %v", err)
+		}
+	}
+
+	if c.Site && c.Output != OutputHTML {
+		return fmt.Errorf("config: site is supported only for html output")
+	}
+
+	if c.SearchIndex && c.Output != OutputHTML {
+		return fmt.Errorf("config: search_index is supported only for html output")
+	}
+
+	c.tmpl = nil
+	if c.TemplateDir != "" && c.Output != OutputHTML {
+		return fmt.Errorf("config: template_dir is supported only for html output")
+	}
+	if c.TemplateDir != "" || c.SearchIndex {
+		var indexURL string
+		if c.SearchIndex {
+			indexURL = searchIndexURL(c.Formatter())
+		}
+		// Errors name the template directory or the template at fault.
+		tmpl, err := loadTemplates(c.TemplateDir, indexURL)
+		if err != nil {
+			return fmt.Errorf("config: %v", err)
+		}
+		c.tmpl = tmpl
+	}
+
+	return nil
+}
+
+// RootMsgs returns the configured root messages.
+func (c *Config) RootMsgs() []protoreflect.FullName {
+	return toFullNames(c.Roots)
+}
+
+// ExtraMsgNames returns the configured extra messages.
+func (c *Config) ExtraMsgNames() []protoreflect.FullName {
+	return toFullNames(c.ExtraMsgs)
+}
+
+func toFullNames(msgs []string) []protoreflect.FullName {
+	var names []protoreflect.FullName
+	for _, msg := range msgs {
+		names = append(names, protoreflect.FullName(msg))
+	}
+	return names
+}
+
+// Formats returns the configured formats. Format can be a comma separated
+// list of formats, to document messages in all of them.
+func (c *Config) Formats() []string {
+	var formats []string
+	for _, format := range strings.Split(c.Format, ",") {
+		formats = append(formats, strings.TrimSpace(format))
+	}
+	return formats
+}
+
+// Formatter returns the formatter for the config, for the first of the
+// configured formats. Config should be validated before calling this method.
+func (c *Config) Formatter() Formatter {
+	format := c.Formats()[0]
+	f := Formatter{}.WithYAML(format == "yaml", c.JSONNames).WithJSON(format == "json").
+		WithHomeURL(c.HomeURL).
+		WithGrouping(c.grouping).
+		WithExternalLinks(c.externalLinks).
+		WithExcludes(c.ExcludeMsgs).
+		WithServices(c.Services)
+
+	// HTML pages are written to their own directories, while markdown pages
+	// are written as files in the output directory. JSON model links to the
+	// HTML pages, relative to the output directory.
+	switch c.Output {
+	case OutputMarkdown:
+		return f.WithPageExt(".md")
+	case OutputJSON:
+		return f
+	}
+	if c.Site {
+		// Link to the page files, so that the site can be browsed locally.
+		return f.WithRelPath("..").WithPageExt("/index.html")
+	}
+	return f.WithRelPath("..")
+}
+
+// Formatters returns the formatters for all the configured formats, see
+// GenerateDocsInFormats.
+func (c *Config) Formatters() []Formatter {
+	var fs []Formatter
+	for _, format := range c.Formats() {
+		fs = append(fs, c.Formatter().WithYAML(format == "yaml", c.JSONNames).WithJSON(format == "json"))
+	}
+	return fs
+}
+
+// Renderer returns the renderer for the config. Config should be validated
+// before calling this method.
+func (c *Config) Renderer() Renderer {
+	r := c.pageRenderer()
+	if c.SiteGenerator != "" {
+		r, _ = NewSiteGeneratorRenderer(c.SiteGenerator, r, c.Title, c.NavPrefix)
+	}
+	if c.SearchIndex {
+		r = NewSearchIndexRenderer(r, c.Formatter())
+	}
+	return r
+}
+
+func (c *Config) pageRenderer() Renderer {
+	if c.Site {
+		var assetsDir string
+		if c.TemplateDir != "" {
+			if fi, err := os.Stat(filepath.Join(c.TemplateDir, SiteAssetsDir)); err == nil && fi.IsDir() {
+				assetsDir = filepath.Join(c.TemplateDir, SiteAssetsDir)
+			}
+		}
+		return NewSiteRenderer(c.tmpl, assetsDir)
+	}
+	if c.tmpl != nil {
+		return NewHTMLRenderer(c.tmpl)
+	}
+	r, _ := NewRenderer(c.Output, c.Formatter())
+	return r
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *Config
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			content: "",
+			want: &Config{
+				Inputs:   InputsConfig{ProtoRootDir: "."},
+				Roots:    []string{"cloudprober.ProberConfig"},
+				Output:   "html",
+				OutDir:   "proto_docs",
+				Format:   "yaml",
+				grouping: DefaultGrouping,
+			},
+		},
+		{
+			name: "full",
+			content: `
+inputs:
+  descriptor_set: protos.pb
+roots: [acme.config.ServerConfig, acme.config.AgentConfig]
+extra_msgs: [acme.config.Rule]
+output: markdown
+out_dir: docs
+format: textpb
+home_url: /docs
+grouping:
+  by: package
+exclude_msgs:
+  - acme.internal.*
+`,
+			want: &Config{
+				Inputs:      InputsConfig{DescriptorSet: "protos.pb"},
+				Roots:       []string{"acme.config.ServerConfig", "acme.config.AgentConfig"},
+				ExtraMsgs:   []string{"acme.config.Rule"},
+				Output:      "markdown",
+				OutDir:      "docs",
+				Format:      "textpb",
+				HomeURL:     "/docs",
+				Grouping:    GroupingConfig{By: "package"},
+				ExcludeMsgs: []string{"acme.internal.*"},
+				grouping:    Grouping{By: GroupByPackage, Root: "cloudprober", Depth: 1},
+			},
+		},
+		{
+			name:    "unknown-field",
+			content: "root_msg: acme.Config",
+			wantErr: true,
+		},
+		{
+			name:    "both-inputs",
+			content: "inputs: {proto_root_dir: protos, descriptor_set: protos.pb}",
+			wantErr: true,
+		},
+		{
+			name:    "roots-and-auto-roots",
+			content: "{roots: [acme.Config], auto_roots: true}",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-root",
+			content: "roots: [acme..Config]",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-output",
+			content: "output: pdf",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-format",
+			content: "format: xml",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-grouping",
+			content: "grouping: {by: dir}",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-grouping-depth",
+			content: "grouping: {depth: -1}",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-external-link",
+			content: "external_links: [{pattern: google.*.Duration, url: https://example.com}]",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-external-link-url",
+			content: "external_links: [{pattern: google.protobuf.*, url: \"{{.Name\"}]",
+			wantErr: true,
+		},
+		{
+			name:    "missing-template-dir",
+			content: "template_dir: /nonexistent/templates",
+			wantErr: true,
+		},
+		{
+			name:    "template-dir-for-markdown",
+			content: "{output: markdown, template_dir: .}",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-exclude",
+			content: "exclude_msgs: ['']",
+			wantErr: true,
+		},
+		{
+			name:    "invalid-services",
+			content: "services: ['acme.*.Service']",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "protodoc.cfg")
+			assert.NoError(t, os.WriteFile(configFile, []byte(tt.content), 0644))
+
+			c, err := LoadConfig(configFile)
+			if err == nil {
+				err = c.Validate()
+			}
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, c)
+		})
+	}
+
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "missing.cfg"))
+	assert.Error(t, err)
+}
+
+func TestConfigFormatter(t *testing.T) {
+	c := &Config{
+		Format:        "yaml",
+		JSONNames:     true,
+		HomeURL:       "/docs/config/overview",
+		ExternalLinks: []ExternalLinkConfig{{Pattern: "cloudprober.probes.http.*", URL: "https://example.com/{{.Name}}"}},
+		ExcludeMsgs:   []string{"cloudprober.probes.dns.*"},
+	}
+	assert.NoError(t, c.Validate())
+
+	f := c.Formatter()
+	assert.True(t, f.yaml)
+	assert.True(t, f.jsonNamesForYAML)
+	assert.Equal(t, "/docs/config/probes#cloudprober_probes_AdditionalLabel", kindToURL("cloudprober.probes.AdditionalLabel", f))
+	assert.Equal(t, "https://example.com/ProbeConf", kindToURL("cloudprober.probes.http.ProbeConf", f))
+	assert.Equal(t, "", kindToURL("cloudprober.probes.dns.ProbeConf", f))
+
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"cloudprober.probes.ProbeDef"}), nil, f, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cloudprober.probes.AdditionalLabel", "cloudprober.probes.udp.ProbeConf"}, msgNames(pages[1]))
+}
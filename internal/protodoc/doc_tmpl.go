@@ -14,8 +14,27 @@
 
 package protodoc
 
+// DocTmpl is the built-in page template. It uses the partials below, which
+// can be overridden individually, see LoadTemplates.
 var DocTmpl = `
-<style>
+{{ template "style" . }}{{ template "search" . }}{{ template "tabs" . }}
+{{- if .Title }}
+<h2>{{ .Title }}</h2>
+{{- end }}
+{{- range .Services -}}
+{{- template "service" . }}
+{{- end -}}
+{{- range .Msgs -}}
+{{- template "message_header" . }}
+{{ template "message_body" . }}
+{{- end -}}
+{{- range .Enums -}}
+{{- template "enum" . }}
+{{- end -}}
+`
+
+// StyleTmpl is the built-in "style" partial, executed with the Page.
+var StyleTmpl = `<style>
 .comment {
     color: #888;
 }
@@ -25,12 +44,113 @@ var DocTmpl = `
     border-radius: 0;
     padding-left: 10px;
 }
-</style>
-{{- range . -}}
-{{- if .Name -}}<h3 id="{{ .Name | replace "." "_" }}">{{ .Name }} <a class="anchor" href="#{{ .Name | replace "." "_" }}">#</a></h3>{{- end }}
+</style>`
+
+// EmptySearchTmpl is the built-in "search" partial, used when the search
+// index is not generated. See SearchTmpl for the search box.
+var EmptySearchTmpl = ``
+
+// MessageHeaderTmpl is the built-in "message_header" partial, executed with
+// the MsgTokens: the message name, followed by its comment.
+var MessageHeaderTmpl = `
+{{- if .Name -}}<h3 id="{{ .Anchor }}">{{ .Name }} <a class="anchor" href="#{{ .Anchor }}">#</a></h3>{{- end }}
+{{- if .Comment }}
+<p>{{ .Comment }}</p>
+{{- end }}`
+
+// MessageBodyTmpl is the built-in "message_body" partial, executed with the
+// MsgTokens. If the message is documented in multiple formats, it renders
+// a tab for each of them.
+var MessageBodyTmpl = `
+{{- if .Formats -}}
+<div class="protodoc-tabs">
+{{- range .Formats }}<button type="button" data-format="{{ .Format }}">{{ .Label }}</button>{{ end -}}
+</div>
+{{- range $i, $ft := .Formats }}
+<pre class="protodoc" data-format="{{ .Format }}"{{ if $i }} hidden{{ end }}>
+
+{{ range .Tokens -}}
+  {{- template "token" . }}
+{{ end -}}
+</pre>
+{{- end }}
+{{- else -}}
 <pre class="protodoc">
 
 {{ range .Tokens -}}
+  {{- template "token" . }}
+{{ end -}}
+</pre>
+{{- end -}}
+`
+
+// TabsTmpl is the built-in "tabs" partial, executed with the Page. If the
+// page is documented in multiple formats, it adds the style and the script
+// for switching between the format tabs. Selected format is remembered
+// across the pages, unless a link points at a field of another format.
+var TabsTmpl = `
+{{- if .Formats }}
+<style>
+.protodoc-tabs button {
+    border: 1px solid #ddd;
+    background: #fff;
+    cursor: pointer;
+}
+.protodoc-tabs button.selected {
+    border-bottom: 2px solid #e6522c;
+    font-weight: bold;
+}
+</style>
+<script>
+(function() {
+  var key = "protodoc-format";
+
+  function selectFormat(format) {
+    if (!document.querySelector('pre.protodoc[data-format="' + format + '"]')) {
+      return;
+    }
+    document.querySelectorAll("pre.protodoc[data-format]").forEach(function(pre) {
+      pre.hidden = pre.dataset.format !== format;
+    });
+    document.querySelectorAll(".protodoc-tabs button").forEach(function(b) {
+      b.classList.toggle("selected", b.dataset.format === format);
+    });
+  }
+
+  document.addEventListener("click", function(e) {
+    var b = e.target.closest(".protodoc-tabs button");
+    if (!b) {
+      return;
+    }
+    try { localStorage.setItem(key, b.dataset.format); } catch (err) {}
+    selectFormat(b.dataset.format);
+  });
+
+  // Field anchors are in the primary format, select it if the linked field
+  // is hidden.
+  function showTarget() {
+    var target = location.hash && document.getElementById(decodeURIComponent(location.hash.slice(1)));
+    var pre = target && target.closest("pre.protodoc[data-format]");
+    if (pre && pre.hidden) {
+      selectFormat(pre.dataset.format);
+      target.scrollIntoView();
+    }
+  }
+  window.addEventListener("hashchange", showTarget);
+
+  document.addEventListener("DOMContentLoaded", function() {
+    var format = null;
+    try { format = localStorage.getItem(key); } catch (err) {}
+    selectFormat(format || {{ index .Formats 0 }});
+    showTarget();
+  });
+})();
+</script>
+{{- end }}`
+
+// TokenTmpl is the built-in "token" partial, executed with the Token.
+var TokenTmpl = `
+  {{- if .Anchor }}<span id="{{ .Anchor }}"></span>{{ end -}}
   {{- if .Comment }}<div class="comment">{{.Comment}}</div>{{ end -}}
   {{- if .URL }}
     {{- .Prefix}}{{.TextHTML}}{{.Sep}}<<a href="{{.URL}}">{{- .Kind}}</a>>{{.Suffix}}
@@ -39,8 +159,5 @@ var DocTmpl = `
   {{- else }}
     {{- .Prefix}}{{.TextHTML}}{{.Suffix}}
   {{- end }}
-  {{- .ExtraLine }}
-{{ end -}}
-</pre>
-{{- end -}}
+  {{- .ExtraLine -}}
 `
@@ -0,0 +1,508 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cloudprober/cloudprober/logger"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// OverviewPage is the name of the page documenting the root message, if
+// there is only one root message.
+const OverviewPage = "overview"
+
+// MsgTokens is the documentation of a single message: its name and the
+// tokens describing its fields.
+type MsgTokens struct {
+	Name   string
+	Tokens []*Token
+
+	// Leading comment of the message.
+	Comment string
+
+	// Tokens in each of the formats, if the message is documented in
+	// multiple formats. The first one is the same as Tokens.
+	Formats []*FormatTokens
+}
+
+// FormatTokens are the tokens describing a message in a config syntax.
+type FormatTokens struct {
+	// Format is "yaml", "textpb" or "json".
+	Format string
+	Label  string
+	Tokens []*Token
+}
+
+var formatLabels = map[string]string{
+	"yaml":   "YAML",
+	"textpb": "textproto",
+	"json":   "JSON",
+}
+
+// Anchor returns the HTML anchor of the message documentation.
+func (m *MsgTokens) Anchor() string {
+	return strings.ReplaceAll(m.Name, ".", "_")
+}
+
+// Page is a documentation page.
+type Page struct {
+	// Name of the page, used as its directory name.
+	Name string
+
+	// Title of the page. It's set only for the root message pages when there
+	// are multiple root messages.
+	Title string
+
+	// Root message documented on this page, set only for the root pages.
+	Root protoreflect.FullName
+
+	// Messages documented on this page.
+	Msgs []*MsgTokens
+
+	// gRPC services documented on this page.
+	Services []*ServiceDoc
+
+	// Enums documented on this page.
+	Enums []*EnumDoc
+
+	// Formats the messages are documented in, if more than one.
+	Formats []string
+}
+
+// Root is a root message to start documentation from. Each root message is
+// documented on its own overview page.
+type Root struct {
+	Msg   protoreflect.FullName
+	Page  string
+	Title string
+}
+
+// NewRoots returns roots for the given messages. A single root message is
+// documented on the OverviewPage, multiple root messages are documented on
+// the pages named after their full names, e.g. acme_config_ServerConfig.
+func NewRoots(msgs []protoreflect.FullName) []*Root {
+	if len(msgs) == 1 {
+		return []*Root{{Msg: msgs[0], Page: OverviewPage}}
+	}
+
+	var roots []*Root
+	for _, msg := range msgs {
+		roots = append(roots, &Root{
+			Msg:   msg,
+			Page:  strings.ReplaceAll(string(msg), ".", "_"),
+			Title: string(msg),
+		})
+	}
+	return roots
+}
+
+// FindRootMessages returns messages defined in the given files that are not
+// referenced by any other message or extension in these files. Map entries,
+// messages from the google.protobuf package, excluded messages, messages
+// documented elsewhere (external links) and RPC request and response messages
+// are ignored.
+func FindRootMessages(fds []protoreflect.FileDescriptor, f Formatter) []protoreflect.FullName {
+	var candidates, rpcMsgDescs []protoreflect.MessageDescriptor
+
+	rpcMsgs := map[protoreflect.FullName]bool{}
+	for _, fd := range fds {
+		for i := 0; i < fd.Services().Len(); i++ {
+			sd := fd.Services().Get(i)
+			for j := 0; j < sd.Methods().Len(); j++ {
+				rpcMsgs[sd.Methods().Get(j).Input().FullName()] = true
+				rpcMsgs[sd.Methods().Get(j).Output().FullName()] = true
+			}
+		}
+	}
+
+	var addMsgs func(mds protoreflect.MessageDescriptors)
+	addMsgs = func(mds protoreflect.MessageDescriptors) {
+		for i := 0; i < mds.Len(); i++ {
+			md := mds.Get(i)
+			if md.IsMapEntry() || f.externalURL(string(md.FullName())) != "" || f.isExcluded(string(md.FullName())) {
+				continue
+			}
+			// RPC messages are documented along with their services, but
+			// messages referenced by them are not roots either.
+			if rpcMsgs[md.FullName()] {
+				rpcMsgDescs = append(rpcMsgDescs, md)
+			} else {
+				candidates = append(candidates, md)
+			}
+			addMsgs(md.Messages())
+		}
+	}
+	for _, fd := range fds {
+		if fd.Package() == "google.protobuf" {
+			continue
+		}
+		addMsgs(fd.Messages())
+	}
+
+	referenced := map[protoreflect.FullName]bool{}
+	var addRefs func(md protoreflect.MessageDescriptor)
+	addRefs = func(md protoreflect.MessageDescriptor) {
+		for i := 0; i < md.Fields().Len(); i++ {
+			fld := md.Fields().Get(i)
+			if fld.Message() == nil {
+				continue
+			}
+			if fld.Message().IsMapEntry() {
+				addRefs(fld.Message())
+				continue
+			}
+			if fld.Message().FullName() != md.FullName() {
+				referenced[fld.Message().FullName()] = true
+			}
+		}
+	}
+	for _, md := range append(candidates, rpcMsgDescs...) {
+		addRefs(md)
+	}
+
+	// Messages used by the extensions are referenced from the extended
+	// messages.
+	addExtRefs := func(exts protoreflect.ExtensionDescriptors) {
+		for i := 0; i < exts.Len(); i++ {
+			if md := exts.Get(i).Message(); md != nil {
+				referenced[md.FullName()] = true
+			}
+		}
+	}
+	for _, fd := range fds {
+		addExtRefs(fd.Extensions())
+	}
+	for _, md := range append(candidates, rpcMsgDescs...) {
+		addExtRefs(md.Extensions())
+	}
+
+	var roots []protoreflect.FullName
+	for _, md := range candidates {
+		if !referenced[md.FullName()] {
+			roots = append(roots, md.FullName())
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i] < roots[j] })
+	return roots
+}
+
+// findMessageInFile finds the message with the given name in the file.
+func findMessageInFile(fd protoreflect.FileDescriptor, name protoreflect.FullName) protoreflect.MessageDescriptor {
+	rel := string(name)
+	if pkg := string(fd.Package()); pkg != "" {
+		if !strings.HasPrefix(rel, pkg+".") {
+			return nil
+		}
+		rel = strings.TrimPrefix(rel, pkg+".")
+	}
+
+	var md protoreflect.MessageDescriptor
+	mds := fd.Messages()
+	for _, part := range strings.Split(rel, ".") {
+		if md = mds.ByName(protoreflect.Name(part)); md == nil {
+			return nil
+		}
+		mds = md.Messages()
+	}
+	return md
+}
+
+// findImportedMessage looks for the message in the files imported by the
+// registered files. Imported files are not necessarily registered, e.g. the
+// well-known types imported by the parsed protos.
+func findImportedMessage(name protoreflect.FullName) protoreflect.MessageDescriptor {
+	var md protoreflect.MessageDescriptor
+	seen := map[string]bool{}
+
+	var search func(fd protoreflect.FileDescriptor) bool
+	search = func(fd protoreflect.FileDescriptor) bool {
+		if seen[fd.Path()] {
+			return true
+		}
+		seen[fd.Path()] = true
+		if md = findMessageInFile(fd, name); md != nil {
+			return false
+		}
+		for i := 0; i < fd.Imports().Len(); i++ {
+			if !search(fd.Imports().Get(i).FileDescriptor) {
+				return false
+			}
+		}
+		return true
+	}
+	Files.RangeFiles(search)
+	return md
+}
+
+func findMessage(name protoreflect.FullName) (protoreflect.MessageDescriptor, error) {
+	d, err := Files.FindDescriptorByName(name)
+	if err != nil {
+		// Protos parsed from a proto root are registered without their
+		// imports, e.g. google/protobuf/duration.proto, but messages from the
+		// imports are documented (and linked) all the same.
+		if md := findImportedMessage(name); md != nil {
+			return md, nil
+		}
+		return nil, fmt.Errorf("error finding message %s: %v", name, err)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message", name)
+	}
+	return md, nil
+}
+
+// dumpMessages dumps the given messages and all the messages reachable from
+// them. It returns a map from message name to its tokens.
+func dumpMessages(msgs []protoreflect.FullName, f Formatter) (map[string][]*Token, error) {
+	f = f.WithDepth(1)
+	msgToDoc := map[string][]*Token{}
+
+	for len(msgs) > 0 {
+		var nextLoop []protoreflect.FullName
+		for _, msgName := range msgs {
+			if _, ok := msgToDoc[string(msgName)]; ok {
+				continue
+			}
+			// Messages documented elsewhere or excluded.
+			if f.externalURL(string(msgName)) != "" || f.isExcluded(string(msgName)) {
+				continue
+			}
+			md, err := findMessage(msgName)
+			if err != nil {
+				return nil, err
+			}
+
+			toks, next, starts := dumpMessage(md, f)
+			setFieldAnchors(msgName, toks, starts)
+			msgToDoc[string(msgName)] = toks
+			nextLoop = append(nextLoop, next...)
+		}
+		msgs = nextLoop
+	}
+	return msgToDoc, nil
+}
+
+// messageComment returns the leading comment of the message as plain text.
+func messageComment(name string) string {
+	md, err := findMessage(protoreflect.FullName(name))
+	if err != nil {
+		return ""
+	}
+	return plainComment(md)
+}
+
+func packagesDocs(msgToDoc map[string][]*Token, services map[string][]*ServiceDoc, enums map[string][]*EnumDoc, f Formatter, l *logger.Logger) []*Page {
+	var msgNames []string
+	for key := range msgToDoc {
+		msgNames = append(msgNames, key)
+	}
+
+	pageByName := map[string]*Page{}
+	var pages []*Page
+	for pkg, msgs := range ArrangeIntoPackages(msgNames, f.Grouping(), l) {
+		sort.Strings(msgs)
+		page := &Page{Name: pkg}
+		for _, msg := range msgs {
+			page.Msgs = append(page.Msgs, &MsgTokens{Name: msg, Tokens: ProcessTokensForHTML(msgToDoc[msg], f), Comment: messageComment(msg)})
+		}
+		pageByName[pkg] = page
+		pages = append(pages, page)
+	}
+	for pkg, sds := range services {
+		page := pageByName[pkg]
+		if page == nil {
+			page = &Page{Name: pkg}
+			pageByName[pkg] = page
+			pages = append(pages, page)
+		}
+		page.Services = sds
+	}
+	for pkg, eds := range enums {
+		page := pageByName[pkg]
+		if page == nil {
+			page = &Page{Name: pkg}
+			pageByName[pkg] = page
+			pages = append(pages, page)
+		}
+		page.Enums = eds
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Name < pages[j].Name })
+	return pages
+}
+
+// GenerateDocs generates documentation pages, starting from the root messages
+// and following all the messages reachable from them and from extraMsgs. Each
+// root message is documented on its own page, followed by the package pages.
+// gRPC services selected by the formatter (see WithServices) are documented
+// on the package pages, along with their request and response messages.
+// Enums used by the documented messages are documented on the package pages
+// too.
+func GenerateDocs(roots []*Root, extraMsgs []protoreflect.FullName, f Formatter, l *logger.Logger) ([]*Page, error) {
+	sds := findServices(f)
+	if len(f.services) != 0 && len(sds) == 0 {
+		return nil, fmt.Errorf("no services matching %v", f.services)
+	}
+	if len(roots) == 0 && len(sds) == 0 {
+		return nil, fmt.Errorf("no root messages to document")
+	}
+
+	var nextMessageNames []protoreflect.FullName
+	rootToks := make([][]*Token, len(roots))
+	rootComments := make([]string, len(roots))
+	for i, root := range roots {
+		m, err := findMessage(root.Msg)
+		if err != nil {
+			return nil, err
+		}
+		toks, next, starts := dumpMessage(m, f.WithDepth(2))
+		setFieldAnchors(root.Msg, toks, starts)
+		rootToks[i] = toks
+		rootComments[i] = plainComment(m)
+		nextMessageNames = append(nextMessageNames, next...)
+	}
+
+	// Package level documentation
+	nextMessageNames = append(nextMessageNames, serviceMessages(sds, f)...)
+	msgToDoc, err := dumpMessages(append(nextMessageNames, extraMsgs...), f)
+	if err != nil {
+		return nil, err
+	}
+
+	enumMsgs := make([]protoreflect.FullName, 0, len(roots)+len(msgToDoc))
+	for _, root := range roots {
+		enumMsgs = append(enumMsgs, root.Msg)
+	}
+	for msg := range msgToDoc {
+		enumMsgs = append(enumMsgs, protoreflect.FullName(msg))
+	}
+	eds, err := findEnums(enumMsgs, f)
+	if err != nil {
+		return nil, err
+	}
+
+	// Link only to the messages and enums that we actually document.
+	documented := make(map[string]bool, len(msgToDoc)+len(eds))
+	for msg := range msgToDoc {
+		documented[msg] = true
+	}
+	for _, ed := range eds {
+		documented[string(ed.FullName())] = true
+	}
+	f = f.withDocumented(documented)
+
+	// Pages are named after the groups, make sure they don't clash.
+	var grouped []string
+	for name := range documented {
+		grouped = append(grouped, name)
+	}
+	for _, sd := range sds {
+		grouped = append(grouped, string(sd.FullName()))
+	}
+	if err := checkGroups(grouped, f.Grouping()); err != nil {
+		return nil, err
+	}
+
+	var pages []*Page
+	for i, root := range roots {
+		pages = append(pages, &Page{
+			Name:  root.Page,
+			Title: root.Title,
+			Root:  root.Msg,
+			Msgs:  []*MsgTokens{{Name: "", Tokens: ProcessTokensForHTML(rootToks[i], f), Comment: rootComments[i]}},
+		})
+	}
+
+	for _, page := range packagesDocs(msgToDoc, servicesDocs(sds, f), enumsDocs(eds, f), f, l) {
+		for _, root := range roots {
+			if page.Name == root.Page {
+				return nil, fmt.Errorf("page name conflict: root message %s and package page %s", root.Msg, page.Name)
+			}
+		}
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
+
+// GenerateDocsInFormats generates the documentation pages, documenting each
+// message in the config syntaxes of all the given formatters. The first
+// formatter is the primary one: it decides the pages and the messages
+// documented on them, and its tokens are used where only one syntax is shown.
+// The other formatters only render the tokens of the same messages.
+func GenerateDocsInFormats(roots []*Root, extraMsgs []protoreflect.FullName, fs []Formatter, l *logger.Logger) ([]*Page, error) {
+	pages, err := GenerateDocs(roots, extraMsgs, fs[0], l)
+	if err != nil || len(fs) == 1 {
+		return pages, err
+	}
+
+	// Link only to the messages and enums documented on the pages, as the
+	// primary formatter does.
+	documented := map[string]bool{}
+	for _, page := range pages {
+		for _, msg := range page.Msgs {
+			if msg.Name != "" {
+				documented[msg.Name] = true
+			}
+		}
+		for _, e := range page.Enums {
+			documented[e.Name] = true
+		}
+	}
+
+	for _, page := range pages {
+		for _, f := range fs {
+			page.Formats = append(page.Formats, f.Format())
+		}
+		for _, msg := range page.Msgs {
+			msg.Formats = []*FormatTokens{{Format: fs[0].Format(), Label: formatLabels[fs[0].Format()], Tokens: msg.Tokens}}
+			for _, f := range fs[1:] {
+				toks, err := formatMsgTokens(page, msg, f.withDocumented(documented))
+				if err != nil {
+					return nil, err
+				}
+				msg.Formats = append(msg.Formats, &FormatTokens{Format: f.Format(), Label: formatLabels[f.Format()], Tokens: toks})
+			}
+		}
+	}
+	return pages, nil
+}
+
+// formatMsgTokens returns the tokens of the message documented on the page,
+// in the formatter's syntax. Root messages are expanded one level deeper, as
+// in GenerateDocs. Field anchors are only set in the primary format, to keep
+// them unique on the page.
+func formatMsgTokens(page *Page, msg *MsgTokens, f Formatter) ([]*Token, error) {
+	name, depth := protoreflect.FullName(msg.Name), 1
+	if msg.Name == "" {
+		name, depth = page.Root, 2
+	}
+	md, err := findMessage(name)
+	if err != nil {
+		return nil, err
+	}
+	toks, _ := DumpMessage(md, f.WithDepth(depth))
+	return ProcessTokensForHTML(toks, f), nil
+}
+
+// WriteDoc renders the documentation page to w, using the built-in
+// templates.
+func WriteDoc(w io.Writer, page *Page) error {
+	return docTmpl.ExecuteTemplate(w, PageTemplate, page)
+}
@@ -0,0 +1,257 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func pageNames(pages []*Page) []string {
+	var names []string
+	for _, page := range pages {
+		names = append(names, page.Name)
+	}
+	return names
+}
+
+func msgNames(page *Page) []string {
+	var names []string
+	for _, m := range page.Msgs {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+func TestGenerateDocs(t *testing.T) {
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"cloudprober.probes.ProbeDef"}), nil, Formatter{}.WithRelPath(".."), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{OverviewPage, "probes"}, pageNames(pages))
+
+	names := msgNames(pages[1])
+	assert.Equal(t, []string{
+		"cloudprober.probes.AdditionalLabel",
+		"cloudprober.probes.dns.ProbeConf",
+		"cloudprober.probes.http.Header",
+		"cloudprober.probes.http.ProbeConf",
+		"cloudprober.probes.udp.ProbeConf",
+	}, names)
+
+	assert.Len(t, pages[0].Msgs, 1)
+	assert.Equal(t, "name", pages[0].Msgs[0].Tokens[0].Text)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteDoc(&buf, pages[1]))
+	assert.Contains(t, buf.String(), `<h3 id="cloudprober_probes_AdditionalLabel">`)
+	assert.NotContains(t, buf.String(), "<h2>")
+
+	_, err = GenerateDocs(NewRoots([]protoreflect.FullName{"cloudprober.probes.Unknown"}), nil, Formatter{}, nil)
+	assert.Error(t, err)
+	_, err = GenerateDocs(NewRoots([]protoreflect.FullName{"cloudprober.probes.ProbeDef"}), []protoreflect.FullName{"cloudprober.probes.ProbeDef.name"}, Formatter{}, nil)
+	assert.Error(t, err)
+	_, err = GenerateDocs(nil, nil, Formatter{}, nil)
+	assert.Error(t, err)
+}
+
+func TestGenerateDocsMessageComments(t *testing.T) {
+	f := Formatter{}.WithRelPath("..").WithGrouping(Grouping{Root: "acme"})
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"acme.config.ServerConfig"}), nil, f, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"overview", "config", "google.protobuf"}, pageNames(pages))
+
+	assert.Equal(t, "Server configuration.", pages[0].Msgs[0].Comment)
+	var buf bytes.Buffer
+	assert.NoError(t, WriteDoc(&buf, pages[0]))
+	assert.Contains(t, buf.String(), "</style>\n<p>Server configuration.</p>\n<pre class=\"protodoc\">")
+
+	// Inlined message's description follows the field's comment.
+	assert.Contains(t, buf.String(), `<div class="comment"># TLS is disabled if not set.
+# TLS settings of the server.</div>tls_config &lt;`)
+
+	handler := pages[1].Msgs[1]
+	assert.Equal(t, "acme.config.Handler", handler.Name)
+	assert.Equal(t, "Handler for a path.", handler.Comment)
+	buf.Reset()
+	assert.NoError(t, WriteDoc(&buf, pages[1]))
+	assert.Contains(t, buf.String(), `<a class="anchor" href="#acme_config_Handler">#</a></h3>
+<p>Handler for a path.</p>`)
+}
+
+func TestGenerateDocsMultipleRoots(t *testing.T) {
+	roots := NewRoots([]protoreflect.FullName{"cloudprober.probes.ProbeDef", "cloudprober.probes.http.ProbeConf"})
+	pages, err := GenerateDocs(roots, nil, Formatter{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cloudprober_probes_ProbeDef", "cloudprober_probes_http_ProbeConf", "probes"}, pageNames(pages))
+	assert.Equal(t, "cloudprober.probes.http.ProbeConf", pages[1].Title)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteDoc(&buf, pages[1]))
+	assert.Contains(t, buf.String(), "<h2>cloudprober.probes.http.ProbeConf</h2>")
+
+	// Root page conflicting with a package page.
+	roots[0].Page = "probes"
+	_, err = GenerateDocs(roots, nil, Formatter{}, nil)
+	assert.Error(t, err)
+}
+
+func TestGenerateDocsInFormats(t *testing.T) {
+	c := &Config{Format: "yaml,textpb"}
+	assert.NoError(t, c.Validate())
+
+	roots := NewRoots([]protoreflect.FullName{"cloudprober.probes.ProbeDef"})
+	pages, err := GenerateDocsInFormats(roots, nil, c.Formatters(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"yaml", "textpb"}, pages[0].Formats)
+
+	msg := pages[0].Msgs[0]
+	assert.Len(t, msg.Formats, 2)
+	assert.Equal(t, "YAML", msg.Formats[0].Label)
+	assert.Equal(t, msg.Tokens, msg.Formats[0].Tokens)
+	assert.Equal(t, "textproto", msg.Formats[1].Label)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteDoc(&buf, pages[0]))
+	out := buf.String()
+	assert.Contains(t, out, `<div class="protodoc-tabs"><button type="button" data-format="yaml">YAML</button><button type="button" data-format="textpb">textproto</button></div>`)
+	assert.Contains(t, out, `<pre class="protodoc" data-format="textpb" hidden>`)
+	assert.Contains(t, out, `selectFormat(format || "yaml");`)
+	// Message headers differ between the formats.
+	assert.Contains(t, out, `cloudprober.probes.http.ProbeConf</a>>:`)
+	assert.Contains(t, out, `cloudprober.probes.http.ProbeConf</a>> {`)
+
+	// Tokens in the other formats are attached to the same messages. Field
+	// anchors are only set in the primary format.
+	textpbPages, err := GenerateDocs(roots, nil, c.Formatters()[1], nil)
+	assert.NoError(t, err)
+	textpbMsgs := map[string][]*Token{}
+	for _, page := range textpbPages {
+		for _, msg := range page.Msgs {
+			for _, tok := range msg.Tokens {
+				tok.Anchor = ""
+			}
+			textpbMsgs[page.Name+"/"+msg.Name] = msg.Tokens
+		}
+	}
+	for _, page := range pages {
+		for _, msg := range page.Msgs {
+			assert.Equal(t, textpbMsgs[page.Name+"/"+msg.Name], msg.Formats[1].Tokens, "page: %s, message: %s", page.Name, msg.Name)
+		}
+	}
+
+	// Single format.
+	pages, err = GenerateDocsInFormats(roots, nil, c.Formatters()[:1], nil)
+	assert.NoError(t, err)
+	assert.Nil(t, pages[0].Formats)
+	assert.Nil(t, pages[0].Msgs[0].Formats)
+
+	assert.Error(t, (&Config{Format: "yaml,yaml"}).Validate())
+	assert.Error(t, (&Config{Format: "yaml,textpb", Output: OutputMarkdown}).Validate())
+}
+
+// TestGenerateDocsInFormatsWKT verifies that the syntaxes that render the
+// well-known types differently still document the same messages.
+func TestGenerateDocsInFormatsWKT(t *testing.T) {
+	roots := NewRoots([]protoreflect.FullName{"acme.config.ServerConfig"})
+	for _, format := range []string{"yaml,textpb", "textpb,yaml", "yaml,json", "json,yaml", "textpb,json", "json,textpb"} {
+		t.Run(format, func(t *testing.T) {
+			c := &Config{Format: format}
+			assert.NoError(t, c.Validate())
+			pages, err := GenerateDocsInFormats(roots, nil, c.Formatters(), nil)
+			assert.NoError(t, err)
+
+			var msgs []string
+			for _, page := range pages {
+				for _, msg := range page.Msgs {
+					assert.Len(t, msg.Formats, 2)
+					if msg.Name != "" {
+						msgs = append(msgs, msg.Name)
+					}
+				}
+			}
+			assert.Contains(t, msgs, "google.protobuf.Duration")
+
+			// Well-known type fields link to their documentation in all the
+			// syntaxes.
+			for _, ft := range pages[0].Msgs[0].Formats {
+				assert.Equal(t, "timeout", strings.Trim(ft.Tokens[1].Text, `"`), ft.Format)
+				assert.NotEmpty(t, ft.Tokens[1].URL, ft.Format)
+			}
+		})
+	}
+}
+
+func TestFindRootMessages(t *testing.T) {
+	var fds []protoreflect.FileDescriptor
+	Files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		fds = append(fds, fd)
+		return true
+	})
+	assert.Equal(t, []protoreflect.FullName{"acme.config.ServerConfig", "cloudprober.probes.ProbeDef"}, FindRootMessages(fds, Formatter{}))
+}
+
+func TestGenerateDocsExternalLinks(t *testing.T) {
+	el, err := ParseExternalLink("cloudprober.probes.http.*=https://example.com/http#{{.Name}}")
+	assert.NoError(t, err)
+
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"cloudprober.probes.ProbeDef"}), nil, Formatter{}.WithExternalLinks([]*ExternalLink{el}), nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"cloudprober.probes.AdditionalLabel", "cloudprober.probes.dns.ProbeConf", "cloudprober.probes.udp.ProbeConf"}, msgNames(pages[1]))
+	for _, tok := range pages[0].Msgs[0].Tokens {
+		if tok.Kind == "oneof" {
+			assert.Contains(t, tok.TextHTML, `<a href="https://example.com/http#ProbeConf">`)
+		}
+	}
+}
+
+func TestFindMessageInImports(t *testing.T) {
+	// Only the parsed file is registered, not the files it imports.
+	withTestProtos(t, map[string]string{"test.proto": `
+syntax = "proto3";
+package test;
+import "google/protobuf/duration.proto";
+import "google/protobuf/struct.proto";
+
+message Config {
+  google.protobuf.Duration timeout = 1;
+  google.protobuf.Value value = 2;
+}
+`})
+	_, err := Files.FindDescriptorByName("google.protobuf.Duration")
+	assert.Error(t, err)
+
+	md, err := findMessage("google.protobuf.Duration")
+	assert.NoError(t, err)
+	assert.Equal(t, "google/protobuf/duration.proto", md.ParentFile().Path())
+
+	// Messages imported indirectly, e.g. through Value.
+	md, err = findMessage("google.protobuf.ListValue")
+	assert.NoError(t, err)
+	assert.Equal(t, protoreflect.FullName("google.protobuf.ListValue"), md.FullName())
+
+	_, err = findMessage("google.protobuf.Unknown")
+	assert.Error(t, err)
+	_, err = findMessage("test.Config.timeout")
+	assert.Error(t, err, "not a message")
+
+	// Imported messages are documented along with the others.
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"test.Config"}), nil, Formatter{}.WithGrouping(Grouping{By: GroupByPackage}), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{OverviewPage, "google.protobuf"}, pageNames(pages))
+}
@@ -0,0 +1,161 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// EnumDoc is the documentation of an enum.
+type EnumDoc struct {
+	// Full name of the enum, e.g. cloudprober.probes.ProbeDef.Type.
+	Name       string
+	Comment    string
+	Deprecated bool
+	Values     []*EnumValueDoc
+}
+
+// Anchor returns the HTML anchor of the enum documentation.
+func (e *EnumDoc) Anchor() string {
+	return strings.ReplaceAll(e.Name, ".", "_")
+}
+
+// EnumValueDoc is the documentation of an enum value.
+type EnumValueDoc struct {
+	Name string
+
+	// HTML anchor of the value, e.g. cloudprober_probes_ProbeDef_Type_HTTP.
+	Anchor string
+
+	Number     int32
+	Comment    string
+	Deprecated bool
+}
+
+// CommentLines returns the comment lines of the enum value.
+func (v *EnumValueDoc) CommentLines() []string {
+	if v.Comment == "" {
+		return nil
+	}
+	return strings.Split(v.Comment, "\n")
+}
+
+// findEnums returns the enums used by the fields (or map values) of the given
+// messages, including their extensions, sorted by name. Enums documented
+// elsewhere or excluded are skipped.
+func findEnums(msgs []protoreflect.FullName, f Formatter) ([]protoreflect.EnumDescriptor, error) {
+	seen := map[protoreflect.FullName]bool{}
+	var eds []protoreflect.EnumDescriptor
+	for _, msg := range msgs {
+		md, err := findMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+		var flds []protoreflect.FieldDescriptor
+		for i := 0; i < md.Fields().Len(); i++ {
+			flds = append(flds, md.Fields().Get(i))
+		}
+		for _, xd := range findExtensions(md) {
+			flds = append(flds, xd)
+		}
+		for _, fld := range flds {
+			if fld.IsMap() {
+				fld = fld.MapValue()
+			}
+			ed := fld.Enum()
+			if ed == nil || seen[ed.FullName()] {
+				continue
+			}
+			seen[ed.FullName()] = true
+			if f.externalURL(string(ed.FullName())) != "" || f.isExcluded(string(ed.FullName())) {
+				continue
+			}
+			eds = append(eds, ed)
+		}
+	}
+	sort.Slice(eds, func(i, j int) bool { return eds[i].FullName() < eds[j].FullName() })
+	return eds, nil
+}
+
+func enumDoc(ed protoreflect.EnumDescriptor) *EnumDoc {
+	opts, _ := ed.Options().(*descriptorpb.EnumOptions)
+	e := &EnumDoc{
+		Name:       string(ed.FullName()),
+		Comment:    plainComment(ed),
+		Deprecated: opts.GetDeprecated(),
+	}
+	for i := 0; i < ed.Values().Len(); i++ {
+		ev := ed.Values().Get(i)
+		valueOpts, _ := ev.Options().(*descriptorpb.EnumValueOptions)
+		e.Values = append(e.Values, &EnumValueDoc{
+			Name:       string(ev.Name()),
+			Anchor:     memberAnchor(e.Name, string(ev.Name())),
+			Number:     int32(ev.Number()),
+			Comment:    plainComment(ev),
+			Deprecated: valueOpts.GetDeprecated(),
+		})
+	}
+	return e
+}
+
+// enumsDocs returns the documentation of the enums, grouped into pages as
+// per the formatter's grouping.
+func enumsDocs(eds []protoreflect.EnumDescriptor, f Formatter) map[string][]*EnumDoc {
+	pages := map[string][]*EnumDoc{}
+	for _, ed := range eds {
+		page := f.Grouping().Group(string(ed.FullName()))
+		pages[page] = append(pages[page], enumDoc(ed))
+	}
+	return pages
+}
+
+// EnumTmpl is the built-in "enum" partial, executed with the EnumDoc. Values
+// are shown in the proto syntax, along with their comments and numbers.
+var EnumTmpl = `
+<h3 id="{{ .Anchor }}">{{ .Name }} <a class="anchor" href="#{{ .Anchor }}">#</a></h3>
+{{- if .Comment }}
+<p>{{ .Comment }}</p>
+{{- end }}
+{{- if .Deprecated }}
+<p>Deprecated.</p>
+{{- end }}
+<pre class="protodoc">
+{{ range .Values }}
+<span id="{{ .Anchor }}"></span>
+{{- range .CommentLines }}<div class="comment">// {{ . }}</div>{{ end -}}
+{{ .Name }} = {{ .Number }}{{ if .Deprecated }} [deprecated = true]{{ end }};
+{{ end -}}
+</pre>`
+
+// enumProto returns the enum values in the proto syntax.
+func enumProto(e *EnumDoc) string {
+	var lines []string
+	for _, v := range e.Values {
+		for _, line := range v.CommentLines() {
+			lines = append(lines, strings.TrimRight("// "+line, " "))
+		}
+		line := fmt.Sprintf("%s = %d", v.Name, v.Number)
+		if v.Deprecated {
+			line += " [deprecated = true]"
+		}
+		lines = append(lines, line+";")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestGenerateDocsEnums(t *testing.T) {
+	f := Formatter{}.WithRelPath("..").WithGrouping(Grouping{Root: "acme"})
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"acme.config.ServerConfig"}), nil, f, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"overview", "config", "google.protobuf"}, pageNames(pages))
+
+	// Enum fields link to the enum documentation.
+	var buf bytes.Buffer
+	assert.NoError(t, WriteDoc(&buf, pages[0]))
+	assert.Contains(t, buf.String(), `log_level: &lt;<a href="../config#acme_config_ServerConfig_LogLevel">acme.config.ServerConfig.LogLevel</a>> | default: ERROR`)
+
+	page := pages[1]
+	assert.Len(t, page.Enums, 1)
+	assert.Equal(t, &EnumDoc{
+		Name:    "acme.config.ServerConfig.LogLevel",
+		Comment: "Verbosity of the logs.",
+		Values: []*EnumValueDoc{
+			{Name: "ERROR", Anchor: "acme_config_ServerConfig_LogLevel_ERROR", Number: 1, Comment: "Only log errors."},
+			{Name: "DEBUG", Anchor: "acme_config_ServerConfig_LogLevel_DEBUG", Number: 2, Comment: "Log everything."},
+			{Name: "VERBOSE", Anchor: "acme_config_ServerConfig_LogLevel_VERBOSE", Number: 3, Comment: "Use DEBUG instead.", Deprecated: true},
+		},
+	}, page.Enums[0])
+
+	buf.Reset()
+	assert.NoError(t, WriteDoc(&buf, page))
+	assert.Contains(t, buf.String(), `<h3 id="acme_config_ServerConfig_LogLevel">`)
+	assert.Contains(t, buf.String(), "<span id=\"acme_config_ServerConfig_LogLevel_VERBOSE\"></span><div class=\"comment\">// Use DEBUG instead.</div>VERBOSE = 3 [deprecated = true];\n")
+
+	assert.Equal(t, "// Only log errors.\nERROR = 1;\n// Log everything.\nDEBUG = 2;\n// Use DEBUG instead.\nVERBOSE = 3 [deprecated = true];", enumProto(page.Enums[0]))
+
+	// Excluded enums are not documented.
+	pages, err = GenerateDocs(NewRoots([]protoreflect.FullName{"acme.config.ServerConfig"}), nil, f.WithExcludes([]string{"acme.config.ServerConfig.LogLevel"}), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, pages[1].Enums)
+}
+
+func TestGenerateDocsExtensionEnums(t *testing.T) {
+	withTestProtos(t, map[string]string{
+		"test.proto": `
+syntax = "proto2";
+package test;
+
+message Config {
+  optional string name = 1;
+  extensions 100 to max;
+}
+
+// Mode of the extension.
+enum Mode {
+  FAST = 1;
+  SAFE = 2;
+}
+
+extend Config {
+  optional Mode mode = 100;
+}
+`,
+	})
+
+	f := Formatter{}.WithGrouping(Grouping{By: GroupByPackage})
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"test.Config"}), nil, f, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"overview", "test"}, pageNames(pages))
+	if assert.Len(t, pages[1].Enums, 1) {
+		assert.Equal(t, "test.Mode", pages[1].Enums[0].Name)
+	}
+
+	// Extension field links to the enum documentation.
+	toks := pages[0].Msgs[0].Tokens
+	assert.Equal(t, "[test.mode]", toks[len(toks)-1].Text)
+	assert.Equal(t, "test#test_Mode", toks[len(toks)-1].URL)
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// extensionRanges returns the extension ranges declared by the message, in
+// the proto syntax, e.g. "200 to max".
+func extensionRanges(md protoreflect.MessageDescriptor) []string {
+	var ranges []string
+	for i := 0; i < md.ExtensionRanges().Len(); i++ {
+		// End of the range is exclusive.
+		r := md.ExtensionRanges().Get(i)
+		start, end := r[0], r[1]-1
+		switch {
+		case end == protowire.MaxValidNumber:
+			ranges = append(ranges, fmt.Sprintf("%d to max", start))
+		case start == end:
+			ranges = append(ranges, fmt.Sprintf("%d", start))
+		default:
+			ranges = append(ranges, fmt.Sprintf("%d to %d", start, end))
+		}
+	}
+	return ranges
+}
+
+// extensionRangesComment returns the leading comments of the message's
+// "extensions" statements, as they appear in the source.
+func extensionRangesComment(md protoreflect.MessageDescriptor) string {
+	if md.ParentFile() == nil {
+		return ""
+	}
+	locs := md.ParentFile().SourceLocations()
+	msgPath := locs.ByDescriptor(md).Path
+	if msgPath == nil {
+		return ""
+	}
+	rangesField := (&descriptorpb.DescriptorProto{}).ProtoReflect().Descriptor().Fields().ByName("extension_range")
+	rangesPath := append(append(protoreflect.SourcePath{}, msgPath...), int32(rangesField.Number()))
+
+	var comments []string
+	for i := 0; i < locs.Len(); i++ {
+		loc := locs.Get(i)
+		if loc.Path.Equal(rangesPath) && strings.TrimSpace(loc.LeadingComments) != "" {
+			comments = append(comments, strings.TrimSuffix(loc.LeadingComments, "\n"))
+		}
+	}
+	if len(comments) == 0 {
+		return ""
+	}
+	return strings.Join(comments, "\n") + "\n"
+}
+
+// extensionsIndex indexes the extensions in the registry by the extended
+// message. It's rebuilt when the registry changes.
+var extensionsIndex struct {
+	sync.Mutex
+	files    *protoregistry.Files
+	numFiles int
+	byMsg    map[protoreflect.FullName][]protoreflect.ExtensionDescriptor
+}
+
+// indexExtensions returns the extensions in the registry by the extended
+// message, sorted by their numbers. Extensions can be declared at the top
+// level of the files or nested in the messages.
+func indexExtensions(files *protoregistry.Files) map[protoreflect.FullName][]protoreflect.ExtensionDescriptor {
+	byMsg := map[protoreflect.FullName][]protoreflect.ExtensionDescriptor{}
+	addExts := func(exts protoreflect.ExtensionDescriptors) {
+		for i := 0; i < exts.Len(); i++ {
+			name := exts.Get(i).ContainingMessage().FullName()
+			byMsg[name] = append(byMsg[name], exts.Get(i))
+		}
+	}
+
+	var addMsgs func(mds protoreflect.MessageDescriptors)
+	addMsgs = func(mds protoreflect.MessageDescriptors) {
+		for i := 0; i < mds.Len(); i++ {
+			addExts(mds.Get(i).Extensions())
+			addMsgs(mds.Get(i).Messages())
+		}
+	}
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		addExts(fd.Extensions())
+		addMsgs(fd.Messages())
+		return true
+	})
+
+	for _, xds := range byMsg {
+		sort.Slice(xds, func(i, j int) bool { return xds[i].Number() < xds[j].Number() })
+	}
+	return byMsg
+}
+
+// findExtensions returns the extensions of the message in the registry,
+// sorted by their numbers.
+func findExtensions(md protoreflect.MessageDescriptor) []protoreflect.ExtensionDescriptor {
+	extensionsIndex.Lock()
+	defer extensionsIndex.Unlock()
+
+	if extensionsIndex.files != Files || extensionsIndex.numFiles != Files.NumFiles() {
+		extensionsIndex.files = Files
+		extensionsIndex.numFiles = Files.NumFiles()
+		extensionsIndex.byMsg = indexExtensions(Files)
+	}
+	return extensionsIndex.byMsg[md.FullName()]
+}
+
+// dumpExtensions dumps the extensions of the message, following its fields.
+// The section is introduced by the comment of the declared extension ranges,
+// and the ranges themselves, even if there are no extensions in the registry.
+func dumpExtensions(md protoreflect.MessageDescriptor, f Formatter) ([]*Token, []protoreflect.FullName, []fieldStart) {
+	ranges := extensionRanges(md)
+	if len(ranges) == 0 {
+		return nil, nil, nil
+	}
+
+	var comments []string
+	if comment := formatCommentText(extensionRangesComment(md), f); comment != "" {
+		comments = append(comments, comment)
+	}
+	comments = append(comments, f.prefix+commentMarker(f)+" Extension numbers: "+strings.Join(ranges, ", ")+".")
+	section := strings.Join(comments, "\n")
+
+	var lines []*Token
+	var nextMessageName []protoreflect.FullName
+	var starts []fieldStart
+	for _, xd := range findExtensions(md) {
+		toks, next := dumpField(xd, f)
+		starts = append(starts, fieldStart{index: len(lines), fld: xd})
+		lines = append(lines, toks...)
+		nextMessageName = append(nextMessageName, next...)
+	}
+
+	if len(lines) == 0 {
+		return []*Token{{Prefix: f.prefix, Comment: section}}, nil, nil
+	}
+	if lines[0].Comment != "" {
+		section += "\n" + lines[0].Comment
+	}
+	lines[0].Comment = section
+	return lines, nextMessageName, starts
+}
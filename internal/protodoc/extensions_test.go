@@ -0,0 +1,106 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestDumpExtensions(t *testing.T) {
+	md, err := findMessage("cloudprober.probes.ProbeDef")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"200 to max"}, extensionRanges(md))
+
+	// Extensions follow the fields, introduced by the extension ranges.
+	toks, next := DumpMessage(md, Formatter{})
+	assert.Equal(t, &Token{
+		Comment: "# Extensions allow users to to add new probe types (for example, a probe type\n" +
+			"# that utilizes a custom protocol) in a systematic manner.\n" +
+			"# Extension numbers: 200 to max.\n" +
+			"# UDP probe, added as an extension.",
+		Kind: "cloudprober.probes.udp.ProbeConf",
+		Text: "[cloudprober.probes.udp.udp_probe]",
+	}, toks[len(toks)-1])
+	assert.Contains(t, next, protoreflect.FullName("cloudprober.probes.udp.ProbeConf"))
+
+	// In YAML and JSON, extension names are quoted.
+	toks, _ = DumpMessage(md, Formatter{}.WithJSON(true))
+	assert.Equal(t, `"[cloudprober.probes.udp.udp_probe]"`, toks[len(toks)-1].Text)
+	toks, _ = DumpMessage(md, Formatter{}.WithYAML(true, false))
+	assert.Equal(t, `"[cloudprober.probes.udp.udp_probe]"`, toks[len(toks)-1].Text)
+
+	// Messages without extension ranges have no extensions section.
+	md, err = findMessage("cloudprober.probes.AdditionalLabel")
+	assert.NoError(t, err)
+	toks, next, starts := dumpExtensions(md, Formatter{})
+	assert.Nil(t, toks)
+	assert.Nil(t, next)
+	assert.Nil(t, starts)
+}
+
+func TestModelExtensions(t *testing.T) {
+	mm, err := modelMessage("cloudprober.probes.ProbeDef", OverviewPage, "overview", Formatter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"200 to max"}, mm.ExtensionRanges)
+	assert.Equal(t, []*ModelField{{
+		Name:     "cloudprober.probes.udp.udp_probe",
+		JSONName: "[cloudprober.probes.udp.udp_probe]",
+		Number:   200,
+		Label:    "optional",
+		Kind:     "cloudprober.probes.udp.ProbeConf",
+		Comment:  "UDP probe, added as an extension.",
+		URL:      "probes#cloudprober_probes_udp_ProbeConf",
+	}}, mm.Extensions)
+}
+
+func TestFindExtensions(t *testing.T) {
+	withTestProtos(t, map[string]string{
+		"base.proto": `
+syntax = "proto2";
+package test;
+
+message Base {
+  extensions 100 to 199;
+}
+`,
+		"ext.proto": `
+syntax = "proto2";
+package test.ext;
+import "base.proto";
+
+message Nested {
+  extend test.Base {
+    optional string nested = 101;
+  }
+}
+
+extend test.Base {
+  optional int32 top = 100;
+}
+`,
+	})
+
+	md, err := findMessage("test.Base")
+	assert.NoError(t, err)
+	var names []protoreflect.FullName
+	for _, xd := range findExtensions(md) {
+		names = append(names, xd.FullName())
+	}
+	assert.Equal(t, []protoreflect.FullName{"test.ext.top", "test.ext.Nested.nested"}, names)
+	assert.Equal(t, []string{"100 to 199"}, extensionRanges(md))
+}
@@ -15,29 +15,50 @@
 package protodoc
 
 import (
-	"flag"
-	"fmt"
 	"html/template"
+	"net/url"
 	"path"
 	"strings"
 
-	"github.com/jhump/protoreflect/desc"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
-var homeURL = flag.String("home_url", "", "Home URL for the documentation.")
+// leadingComment returns the leading comment of the descriptor, as it appears
+// in the source, i.e. without the comment markers. Descriptors that come
+// without source info (e.g. well-known types) have no comments.
+func leadingComment(d protoreflect.Descriptor) string {
+	if d.ParentFile() == nil {
+		return ""
+	}
+	return d.ParentFile().SourceLocations().ByDescriptor(d).LeadingComments
+}
 
-func formatComment(fld protoreflect.Descriptor, f Formatter) string {
-	ff, err := Files.FindDescriptorByName(fld.FullName())
-	if err != nil {
-		panic(err)
+// plainComment returns the leading comment of the descriptor as plain text,
+// without the leading space on each line and surrounding blank lines.
+func plainComment(d protoreflect.Descriptor) string {
+	lines := strings.Split(leadingComment(d), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(strings.TrimRight(line, " "), " ")
 	}
-	wf, err := desc.WrapDescriptor(ff)
-	if err != nil {
-		panic(err)
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// commentMarker returns the marker used for the comments. JSON doesn't have
+// comments, we use the JSONC ones.
+func commentMarker(f Formatter) string {
+	if f.json {
+		return "//"
 	}
+	return "#"
+}
+
+func formatComment(fld protoreflect.Descriptor, f Formatter) string {
+	return formatCommentText(leadingComment(fld), f)
+}
 
-	comment := wf.GetSourceInfo().GetLeadingComments()
+// formatCommentText formats the comment, as it appears in the source, for the
+// config syntax.
+func formatCommentText(comment string, f Formatter) string {
 	if comment != "" && strings.TrimSpace(comment) != "" {
 		var temp []string
 		lines := strings.Split(comment, "\n")
@@ -45,107 +66,74 @@ func formatComment(fld protoreflect.Descriptor, f Formatter) string {
 			if i == len(lines)-1 && strings.TrimSpace(line) == "" {
 				continue
 			}
-			temp = append(temp, f.prefix+"#"+line)
+			temp = append(temp, f.prefix+commentMarker(f)+line)
 		}
 		comment = strings.Join(temp, "\n")
 	}
 	return comment
 }
 
-func formatOneOf(ood protoreflect.OneofDescriptor, f Formatter) *Token {
-	oof := ood.Fields()
-	oneofFields := []string{}
-
-	for i := 0; i < oof.Len(); i++ {
-		fld := oof.Get(i)
-
-		if fldEnum := fld.Enum(); fldEnum != nil {
-			// We get token text from finalToken and kind string from enum
-			tok := finalToken(fld, f, true)
-			oneofFields = append(oneofFields, formatEnum(fldEnum, tok.Text, f).Text)
-			continue
-		}
-
-		tok := finalToken(oof.Get(i), f, true)
-		s := fmt.Sprintf("%s &lt;%s&gt;", tok.Text, tok.Kind)
-		if strings.HasPrefix(tok.Kind, "cloudprober.") {
-			s = fmt.Sprintf("%s &lt;<a href=\"%s\">%s</a>&gt;", tok.Text, kindToURL(tok.Kind, f), tok.Kind)
-		}
-		oneofFields = append(oneofFields, s)
+// oneofComment returns the comment added before the first alternative of a
+// oneof: the oneof's comment, followed by the list of its alternatives. It
+// returns an empty string for the other fields.
+func oneofComment(fld protoreflect.FieldDescriptor, f Formatter) string {
+	oo := fld.ContainingOneof()
+	// In proto3, optional fields have a synthetic oneof container.
+	if oo == nil || oo.IsSynthetic() || oo.Fields().Get(0) != fld {
+		return ""
 	}
 
-	text := "["
-	for i, tok := range oneofFields {
-		if i != 0 && i%2 == 0 {
-			text += "\n" + strings.ReplaceAll(f.prefix+" ", " ", "&nbsp;")
-		}
-		if i == len(oneofFields)-1 {
-			text += tok + "]"
-			break
+	var names []string
+	for i := 0; i < oo.Fields().Len(); i++ {
+		name := string(oo.Fields().Get(i).Name())
+		if f.useJSONNames() {
+			name = oo.Fields().Get(i).JSONName()
 		}
-		text += tok + " | "
-	}
-	return &Token{
-		Comment:  formatComment(ood, f),
-		Kind:     "oneof",
-		Prefix:   f.prefix,
-		TextHTML: template.HTML(text),
+		names = append(names, name)
 	}
-}
 
-func formatEnum(ed protoreflect.EnumDescriptor, name string, f Formatter) *Token {
-	enumVals := []string{}
-	for i := 0; i < ed.Values().Len(); i++ {
-		enumVals = append(enumVals, string(ed.Values().Get(i).Name()))
-	}
-	return &Token{
-		Kind:   "enum",
-		Prefix: f.prefix,
-		Text:   fmt.Sprintf("%s: (%s)", name, strings.Join(enumVals, "|")),
+	var lines []string
+	if comment := formatComment(oo, f); comment != "" {
+		lines = append(lines, comment)
 	}
+	lines = append(lines, f.prefix+commentMarker(f)+" Only one of: "+strings.Join(names, ", ")+".")
+	return strings.Join(lines, "\n")
 }
 
-func fieldToToken(fld protoreflect.FieldDescriptor, f Formatter, done *map[string]bool) *Token {
-	if oo := fld.ContainingOneof(); oo != nil {
-		// In proto3, optional fields have an oneof container.
-		if oo.Fields().Len() == 1 {
-			return finalToken(fld, f, false)
-		}
-		if (*done)[string(oo.Name())] {
-			return nil
-		}
-		(*done)[string(oo.Name())] = true
-		return formatOneOf(oo, f)
-	}
-
-	if ed := fld.Enum(); ed != nil {
-		name := string(fld.Name())
-		if f.yaml && f.jsonNamesForYAML {
-			name = fld.JSONName()
-		}
-		tok := formatEnum(ed, name, f)
-		tok.Comment = formatComment(fld, f)
-		return tok
+// linkedKind returns the type linked from the token's kind.
+func (tok *Token) linkedKind() string {
+	if tok.linkKind != "" {
+		return tok.linkKind
 	}
-
-	return finalToken(fld, f, false)
+	return tok.Kind
 }
 
 func ProcessTokensForHTML(toks []*Token, f Formatter) []*Token {
 	for _, tok := range toks {
-		tok.URL = kindToURL(tok.Kind, f)
+		tok.URL = kindToURL(tok.linkedKind(), f)
 
 		if tok.MessageHeader {
 			tok.Suffix = " {"
 			if tok.yaml {
 				tok.Suffix = ":"
 			}
+			if tok.json {
+				tok.Suffix = ": {"
+				if tok.repeated {
+					tok.Suffix = ": [{"
+				}
+			}
 			tok.Sep = " "
 		} else {
 			if tok.Default != "" {
 				tok.Suffix = template.HTML(" | default: " + tok.Default)
 			}
 			tok.Sep = ": "
+			// Repeated fields are arrays in JSON.
+			if tok.json && tok.repeated {
+				tok.Sep = ": ["
+				tok.Suffix = "]"
+			}
 		}
 
 		if tok.TextHTML == "" {
@@ -160,14 +148,53 @@ func ProcessTokensForHTML(toks []*Token, f Formatter) []*Token {
 	return toks
 }
 
+func (f Formatter) isExcluded(kind string) bool {
+	for _, pattern := range f.excludes {
+		if matchPattern(pattern, kind) != -1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Formatter) isDocumented(kind string) bool {
+	if kind == "" || f.isExcluded(kind) {
+		return false
+	}
+	if f.documented != nil {
+		return f.documented[kind]
+	}
+	_, err := Files.FindDescriptorByName(protoreflect.FullName(kind))
+	return err == nil
+}
+
+// kindToURL returns the URL of the documentation of the given kind. Types
+// matching an external link are linked to their external documentation,
+// other documented types are linked to the page they are grouped into. It
+// returns an empty string if kind is not documented.
 func kindToURL(kind string, f Formatter) string {
-	if !strings.HasPrefix(kind, "cloudprober.") {
+	if url := f.externalURL(kind); url != "" {
+		return url
+	}
+	if !f.isDocumented(kind) {
 		return ""
 	}
-	parts := strings.SplitN(kind, ".", 3)
-	if len(parts) > 2 {
-		kindForURL := strings.ReplaceAll(kind, ".", "_")
-		return path.Join(*homeURL, f.relPath, parts[1]+"#"+kindForURL)
+	kindForURL := strings.ReplaceAll(kind, ".", "_")
+	return f.docURL(f.Grouping().Group(kind) + f.pageExt + "#" + kindForURL)
+}
+
+// docURL returns the URL of the documentation file (e.g. a page, with an
+// optional anchor), relative to the home URL and the formatter's relative
+// path. Home URL can also be an absolute URL, e.g. https://example.com/docs,
+// whose path is joined separately, as joining the whole URL would collapse
+// its "//".
+func (f Formatter) docURL(rel string) string {
+	rel = path.Join(f.relPath, rel)
+	if u, err := url.Parse(f.homeURL); err == nil && u.Scheme != "" {
+		page, anchor, _ := strings.Cut(rel, "#")
+		u.Path, u.RawPath = path.Join("/", u.Path, page), ""
+		u.Fragment, u.RawFragment = anchor, ""
+		return u.String()
 	}
-	return ""
+	return path.Join(f.homeURL, rel)
 }
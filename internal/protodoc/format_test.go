@@ -96,36 +96,26 @@ func TestFinalToToken(t *testing.T) {
 	}
 }
 
-func TestFormatOneOf(t *testing.T) {
+func TestOneofComment(t *testing.T) {
 	const fldName = "cloudprober.probes.ProbeDef.http_probe"
-	// Used for duplication test for oneof fileds.
+	// Only the first alternative introduces the oneof.
 	const fldName2 = "cloudprober.probes.ProbeDef.dns_probe"
 
 	tests := []struct {
 		name string
 		f    Formatter
-		want *Token
+		want string
 	}{
 		{
 			name: "default",
-			want: &Token{
-				Kind:    "oneof",
-				Comment: "# Define one probe type",
-				TextHTML: `[http_probe &lt;<a href="probes#cloudprober_probes_http_ProbeConf">cloudprober.probes.http.ProbeConf</a>&gt; | dns_probe &lt;<a href="probes#cloudprober_probes_dns_ProbeConf">cloudprober.probes.dns.ProbeConf</a>&gt; | 
-&nbsp;user_defined_probe: (NO_CONFIG|CUSTOM_CONFIG)]`,
-			},
+			want: "# Define one probe type\n# Only one of: http_probe, dns_probe, user_defined_probe.",
 		},
 		{
 			name: "yaml",
 			f: Formatter{
 				yaml: true,
 			},
-			want: &Token{
-				Kind:    "oneof",
-				Comment: "# Define one probe type",
-				TextHTML: `[http_probe &lt;<a href="probes#cloudprober_probes_http_ProbeConf">cloudprober.probes.http.ProbeConf</a>&gt; | dns_probe &lt;<a href="probes#cloudprober_probes_dns_ProbeConf">cloudprober.probes.dns.ProbeConf</a>&gt; | 
-&nbsp;user_defined_probe: (NO_CONFIG|CUSTOM_CONFIG)]`,
-			},
+			want: "# Define one probe type\n# Only one of: http_probe, dns_probe, user_defined_probe.",
 		},
 		{
 			name: "yaml with json",
@@ -133,43 +123,176 @@ func TestFormatOneOf(t *testing.T) {
 				yaml:             true,
 				jsonNamesForYAML: true,
 			},
-			want: &Token{
-				Kind:    "oneof",
-				Comment: "# Define one probe type",
-				TextHTML: `[httpProbe &lt;<a href="probes#cloudprober_probes_http_ProbeConf">cloudprober.probes.http.ProbeConf</a>&gt; | dnsProbe &lt;<a href="probes#cloudprober_probes_dns_ProbeConf">cloudprober.probes.dns.ProbeConf</a>&gt; | 
-&nbsp;userDefinedProbe: (NO_CONFIG|CUSTOM_CONFIG)]`,
-			},
+			want: "# Define one probe type\n# Only one of: httpProbe, dnsProbe, userDefinedProbe.",
+		},
+		{
+			name: "json",
+			f:    Formatter{}.WithJSON(true),
+			want: "// Define one probe type\n// Only one of: httpProbe, dnsProbe, userDefinedProbe.",
 		},
 		{
 			name: "with-prefix",
 			f: Formatter{
 				prefix: "  ",
 			},
-			want: &Token{
-				Kind:    "oneof",
-				Comment: "  # Define one probe type",
-				Prefix:  "  ",
-				TextHTML: `[http_probe &lt;<a href="probes#cloudprober_probes_http_ProbeConf">cloudprober.probes.http.ProbeConf</a>&gt; | dns_probe &lt;<a href="probes#cloudprober_probes_dns_ProbeConf">cloudprober.probes.dns.ProbeConf</a>&gt; | 
-&nbsp;&nbsp;&nbsp;user_defined_probe: (NO_CONFIG|CUSTOM_CONFIG)]`,
-			},
+			want: "  # Define one probe type\n  # Only one of: http_probe, dns_probe, user_defined_probe.",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			desc, err := Files.FindDescriptorByName(protoreflect.FullName(fldName))
 			assert.NoError(t, err)
-			fld := desc.(protoreflect.FieldDescriptor)
+			assert.Equal(t, tt.want, oneofComment(desc.(protoreflect.FieldDescriptor), tt.f))
 
-			done := map[string]bool{}
-			assert.Equal(t, tt.want, fieldToToken(fld, tt.f, &done))
-
-			// Make sure we get nil for the second field from same oneof.
 			desc, err = Files.FindDescriptorByName(protoreflect.FullName(fldName2))
 			assert.NoError(t, err)
-			fld = desc.(protoreflect.FieldDescriptor)
-			assert.Nil(t, fieldToToken(fld, tt.f, &done))
+			assert.Equal(t, "", oneofComment(desc.(protoreflect.FieldDescriptor), tt.f))
+		})
+	}
+}
+
+func TestOneofCommentSingleAlternative(t *testing.T) {
+	withTestProtos(t, map[string]string{
+		"test.proto": `
+syntax = "proto3";
+package test;
 
-			assert.Equal(t, tt.want, formatOneOf(fld.ContainingOneof(), tt.f))
+message Config {
+  oneof source {
+    string file_path = 1;
+  }
+  optional string name = 2;
+}
+`,
+	})
+
+	md, err := findMessage("test.Config")
+	assert.NoError(t, err)
+	// Proto3 optional fields are in synthetic oneofs, that are not documented.
+	assert.Equal(t, "# Only one of: file_path.", oneofComment(md.Fields().ByName("file_path"), Formatter{}))
+	assert.Equal(t, "", oneofComment(md.Fields().ByName("name"), Formatter{}))
+}
+
+func TestDumpMessageOneof(t *testing.T) {
+	md, err := findMessage("acme.config.Handler")
+	assert.NoError(t, err)
+
+	// Each alternative is on its own line, with its comment and link.
+	toks, next := DumpMessage(md, Formatter{}.WithDepth(1))
+	assert.Equal(t, []*Token{
+		{Comment: "# Only one of: file_dir, backend.\n# Serve files from a directory.", Kind: "string", Text: "file_dir"},
+		{Comment: "# Proxy requests to a backend.", Kind: "acme.config.Backend", Text: "backend"},
+		{Kind: "bool", Text: "enabled", Default: "true"},
+	}, toks)
+	assert.Equal(t, []protoreflect.FullName{"acme.config.Backend"}, next)
+
+	// Message alternatives are inlined, if depth allows.
+	toks, _ = DumpMessage(md, Formatter{}.WithDepth(2))
+	assert.Equal(t, []string{
+		"file_dir: string",
+		"backend acme.config.Backend {",
+		"  address: string",
+		"  fallback: acme.config.Backend",
+		"}",
+		"enabled: bool | default: true",
+	}, tokenLines(ProcessTokensForHTML(toks, Formatter{})))
+	assert.Equal(t, "# Proxy requests to a backend.", toks[1].Comment)
+}
+
+func TestDumpMessageEmptyMessages(t *testing.T) {
+	withTestProtos(t, map[string]string{"test.proto": `
+syntax = "proto2";
+package test;
+
+message Empty {}
+
+message Config {
+  repeated Empty items = 1;
+  oneof source {
+    Empty none = 2;
+    string path = 3;
+  }
+}
+`})
+	md, err := findMessage("test.Config")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		f    Formatter
+		want []string
+	}{
+		{
+			f:    Formatter{},
+			want: []string{"items test.Empty {", "}", "none test.Empty {", "}", "path: string"},
+		},
+		{
+			f:    Formatter{}.WithYAML(true, false),
+			want: []string{"items test.Empty:", "  - {}", "none test.Empty:", "  {}", "path: string"},
+		},
+		{
+			f:    Formatter{}.WithJSON(true),
+			want: []string{`"items" test.Empty: [{`, "}]", `"none" test.Empty: {`, "}", `"path": string`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.f.Format(), func(t *testing.T) {
+			toks, _ := DumpMessage(md, tt.f.WithDepth(2))
+			assert.Equal(t, tt.want, tokenLines(ProcessTokensForHTML(toks, tt.f)))
+		})
+	}
+}
+
+func TestDumpMessageMap(t *testing.T) {
+	md, err := findMessage("acme.config.ServerConfig")
+	assert.NoError(t, err)
+	handlers := md.Fields().ByName("handlers")
+
+	// Map is shown on a single line at depth 1, linking to its value type.
+	f := Formatter{}.WithDepth(1)
+	toks, next := dumpMap(handlers, f)
+	assert.Len(t, toks, 1)
+	assert.Equal(t, "map<string, acme.config.Handler>", toks[0].Kind)
+	assert.Equal(t, []protoreflect.FullName{"acme.config.Handler"}, next)
+
+	toks = ProcessTokensForHTML(toks, f.withDocumented(map[string]bool{"acme.config.Handler": true}))
+	assert.Equal(t, "acme.config#acme_config_Handler", toks[0].URL)
+
+	tests := []struct {
+		name string
+		f    Formatter
+		want []string
+	}{
+		{
+			name: "textpb",
+			f:    Formatter{},
+			want: []string{
+				"handlers map<string, acme.config.Handler> {",
+				"  key: string",
+				"  value {",
+				"    file_dir: string",
+				"    backend: acme.config.Backend",
+				"    enabled: bool | default: true",
+				"  }",
+				"}",
+			},
+		},
+		{
+			name: "yaml",
+			f:    Formatter{}.WithYAML(true, false),
+			want: []string{
+				"handlers map<string, acme.config.Handler>:",
+				"  <string>:",
+				"    file_dir: string",
+				"    backend: acme.config.Backend",
+				"    enabled: bool | default: true",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toks, next := dumpMap(handlers, tt.f.WithDepth(2))
+			assert.Equal(t, tt.want, tokenLines(ProcessTokensForHTML(toks, tt.f)))
+			assert.Equal(t, []protoreflect.FullName{"acme.config.Handler", "acme.config.Backend"}, next)
 		})
 	}
 }
@@ -186,8 +309,8 @@ func TestFormatEnum(t *testing.T) {
 			name: "default",
 			want: &Token{
 				Comment: "# Select probe type",
-				Kind:    "enum",
-				Text:    "type: (HTTP|TCP|EXTENSION|USER_DEFINED)",
+				Kind:    "cloudprober.probes.ProbeDef.Type",
+				Text:    "type",
 			},
 		},
 		{
@@ -197,9 +320,9 @@ func TestFormatEnum(t *testing.T) {
 			},
 			want: &Token{
 				Comment: "  # Select probe type",
-				Kind:    "enum",
+				Kind:    "cloudprober.probes.ProbeDef.Type",
 				Prefix:  "  ",
-				Text:    "type: (HTTP|TCP|EXTENSION|USER_DEFINED)",
+				Text:    "type",
 			},
 		},
 	}
@@ -210,11 +333,11 @@ func TestFormatEnum(t *testing.T) {
 
 			fld := desc.(protoreflect.FieldDescriptor)
 
-			done := map[string]bool{}
-			assert.Equal(t, tt.want, fieldToToken(fld, tt.f, &done), "fieldToToken")
-			wantEnumToken := *tt.want
-			wantEnumToken.Comment = ""
-			assert.Equal(t, &wantEnumToken, formatEnum(fld.Enum(), "type", tt.f))
+			tok := finalToken(fld, tt.f, false)
+			assert.Equal(t, tt.want, tok)
+
+			// Enum fields link to the enum documentation.
+			assert.Equal(t, "probes#cloudprober_probes_ProbeDef_Type", kindToURL(tok.Kind, tt.f))
 		})
 	}
 }
@@ -321,6 +444,17 @@ func TestProcessTokensForHTML(t *testing.T) {
 }
 
 func TestKindToURL(t *testing.T) {
+	var links []*ExternalLink
+	for _, s := range []string{
+		"google.protobuf.*=https://protobuf.dev/reference/protobuf/google.protobuf/#{{.Name}}",
+		"cloudprober.probes.http.*=https://example.com/{{.Package}}/{{.FullName}}",
+		"cloudprober.probes.http.Header=https://example.com/header",
+	} {
+		el, err := ParseExternalLink(s)
+		assert.NoError(t, err)
+		links = append(links, el)
+	}
+
 	tests := []struct {
 		f    Formatter
 		kind string
@@ -343,10 +477,56 @@ func TestKindToURL(t *testing.T) {
 			kind: "probes.ProbeDef.interval_msec",
 			want: "",
 		},
+		{
+			kind: "cloudprober.probes.http.ProbeConf",
+			want: "../cloudprober.probes.http#cloudprober_probes_http_ProbeConf",
+			f:    Formatter{}.WithRelPath("..").WithGrouping(Grouping{By: GroupByPackage}),
+		},
+		{
+			kind: "cloudprober.probes.http.ProbeConf",
+			want: "probes#cloudprober_probes_http_ProbeConf",
+			f:    Formatter{}.withDocumented(map[string]bool{"cloudprober.probes.http.ProbeConf": true}),
+		},
+		{
+			kind: "cloudprober.probes.dns.ProbeConf",
+			want: "",
+			f:    Formatter{}.withDocumented(map[string]bool{"cloudprober.probes.http.ProbeConf": true}),
+		},
+		{
+			kind: "google.protobuf.Duration",
+			want: "https://protobuf.dev/reference/protobuf/google.protobuf/#Duration",
+			f:    Formatter{}.WithExternalLinks(links),
+		},
+		{
+			kind: "cloudprober.probes.http.ProbeConf",
+			want: "https://example.com/cloudprober.probes.http/cloudprober.probes.http.ProbeConf",
+			f:    Formatter{}.WithRelPath("..").WithExternalLinks(links),
+		},
+		{
+			kind: "cloudprober.probes.http.Header",
+			want: "https://example.com/header",
+			f:    Formatter{}.WithExternalLinks(links),
+		},
+		{
+			kind: "cloudprober.probes.dns.ProbeConf",
+			want: "../probes#cloudprober_probes_dns_ProbeConf",
+			f:    Formatter{}.WithRelPath("..").WithExternalLinks(links),
+		},
 	}
 	for _, tt := range tests {
-		t.Run(tt.kind, func(t *testing.T) {
+		t.Run(tt.kind+"->"+tt.want, func(t *testing.T) {
 			assert.Equal(t, tt.want, kindToURL(tt.kind, tt.f))
 		})
 	}
 }
+
+func TestParseExternalLink(t *testing.T) {
+	for _, s := range []string{"google.protobuf.*", "=https://example.com", "google.*=", "google.*={{.Name"} {
+		_, err := ParseExternalLink(s)
+		assert.Error(t, err, s)
+	}
+
+	el, err := ParseExternalLink("google.type.* = https://example.com/{{.Name}}")
+	assert.NoError(t, err)
+	assert.Equal(t, "google.type.*", el.Pattern)
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// GroupBy specifies how messages are grouped into documentation pages.
+type GroupBy int
+
+const (
+	// GroupByPrefix groups messages by the first N package components after
+	// the root package, e.g. with root "cloudprober" and depth 1,
+	// cloudprober.probes.http.ProbeConf goes to the "probes" page.
+	GroupByPrefix GroupBy = iota
+	// GroupByPackage groups messages by their full proto package.
+	GroupByPackage
+	// GroupByFile groups messages by the proto file they are defined in.
+	GroupByFile
+)
+
+var groupByNames = map[string]GroupBy{
+	"prefix":  GroupByPrefix,
+	"package": GroupByPackage,
+	"file":    GroupByFile,
+}
+
+// ParseGroupBy parses the grouping strategy name: prefix, package or file.
+func ParseGroupBy(s string) (GroupBy, error) {
+	g, ok := groupByNames[s]
+	if !ok {
+		return 0, fmt.Errorf("invalid grouping: %s, should be one of prefix, package or file", s)
+	}
+	return g, nil
+}
+
+// Grouping configures how messages are arranged into documentation pages.
+type Grouping struct {
+	By GroupBy
+
+	// Root package and depth used by GroupByPrefix. Messages outside the root
+	// package are grouped by their full package.
+	Root  string
+	Depth int
+}
+
+// DefaultGrouping groups cloudprober messages by their top-level package,
+// e.g. cloudprober.probes.*, cloudprober.targets.*, etc.
+var DefaultGrouping = Grouping{By: GroupByPrefix, Root: "cloudprober", Depth: 1}
+
+// defaultGroup is used for messages that don't have a package.
+const defaultGroup = "default"
+
+// packageOf returns the proto package of the given name. If the name is not
+// found in the registry, everything before its last component is assumed to
+// be the package.
+func packageOf(name string) string {
+	if d, err := Files.FindDescriptorByName(protoreflect.FullName(name)); err == nil {
+		return string(d.ParentFile().Package())
+	}
+	if i := strings.LastIndex(name, "."); i != -1 {
+		return name[:i]
+	}
+	return ""
+}
+
+func fileGroup(name string) string {
+	d, err := Files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return packageGroup(packageOf(name))
+	}
+	p := strings.TrimSuffix(d.ParentFile().Path(), ".proto")
+	return strings.NewReplacer("/", "_", ".", "_").Replace(p)
+}
+
+func packageGroup(pkg string) string {
+	if pkg == "" {
+		return defaultGroup
+	}
+	return pkg
+}
+
+// Group returns the name of the group (documentation page) the given message
+// belongs to.
+func (g Grouping) Group(name string) string {
+	group, _ := g.group(name)
+	return group
+}
+
+// group returns the name of the group the given message belongs to, and
+// what the group stands for: the package (or package prefix), or the file.
+// Different packages or files may end up with the same group name, see
+// checkGroups.
+func (g Grouping) group(name string) (group, scope string) {
+	switch g.By {
+	case GroupByPackage:
+		pkg := packageOf(name)
+		return packageGroup(pkg), pkg
+	case GroupByFile:
+		if d, err := Files.FindDescriptorByName(protoreflect.FullName(name)); err == nil {
+			return fileGroup(name), d.ParentFile().Path()
+		}
+		pkg := packageOf(name)
+		return packageGroup(pkg), pkg
+	}
+
+	pkg := packageOf(name)
+	prefix := ""
+	if g.Root != "" {
+		if pkg == g.Root {
+			return g.Root[strings.LastIndex(g.Root, ".")+1:], pkg
+		}
+		if !strings.HasPrefix(pkg, g.Root+".") {
+			return packageGroup(pkg), pkg
+		}
+		pkg = strings.TrimPrefix(pkg, g.Root+".")
+		prefix = g.Root + "."
+	}
+
+	depth := g.Depth
+	if depth <= 0 {
+		depth = 1
+	}
+	parts := strings.Split(pkg, ".")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	group = packageGroup(strings.Join(parts, "."))
+	if pkg == "" {
+		return group, ""
+	}
+	return group, prefix + strings.Join(parts, ".")
+}
+
+// checkGroups returns an error if the given messages (or services and
+// enums) from different packages or files end up on the same page, or on the
+// overview page, which is reserved for the root message.
+func checkGroups(names []string, g Grouping) error {
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+
+	scopes := map[string]string{}
+	for _, name := range names {
+		group, scope := g.group(name)
+		if group == OverviewPage {
+			return fmt.Errorf("page name conflict: %s goes to the reserved page %s", name, group)
+		}
+		if s, ok := scopes[group]; ok && s != scope {
+			return fmt.Errorf("page name conflict: %q and %q both go to the page %s, change the grouping to tell them apart", s, scope, group)
+		}
+		scopes[group] = scope
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"regexp"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// HTTPBinding is an HTTP/JSON transcoding binding of an RPC, as specified by
+// the google.api.http method option.
+type HTTPBinding struct {
+	// HTTP method (GET, PUT, POST, DELETE or PATCH), or the custom method.
+	Method string `json:"method"`
+
+	// Path template, e.g. "/v1/{name=servers/*}".
+	Path string `json:"path"`
+
+	// Request field mapped to the request body: "*" for all the fields not
+	// bound by the path, or empty if there is no request body.
+	Body string `json:"body,omitempty"`
+
+	// Response field mapped to the response body, empty for the whole
+	// response.
+	ResponseBody string `json:"response_body,omitempty"`
+
+	// Request fields bound by the path template and the ones that become
+	// query parameters, as field paths, e.g. "filter.name".
+	PathParams  []string `json:"path_params,omitempty"`
+	QueryParams []string `json:"query_params,omitempty"`
+
+	// Example request body in the JSON form.
+	ExampleBody string `json:"example_body,omitempty"`
+}
+
+// Text returns a plain text description of the binding: the method and the
+// path, followed by the path parameters, query parameters and body.
+func (b *HTTPBinding) Text() string {
+	lines := []string{b.Method + " " + b.Path}
+	if len(b.PathParams) != 0 {
+		lines = append(lines, "Path parameters: "+strings.Join(b.PathParams, ", "))
+	}
+	if len(b.QueryParams) != 0 {
+		lines = append(lines, "Query parameters: "+strings.Join(b.QueryParams, ", "))
+	}
+	switch b.Body {
+	case "":
+	case "*":
+		lines = append(lines, "Body: request fields other than the path parameters")
+	default:
+		lines = append(lines, "Body: "+b.Body)
+	}
+	if b.ResponseBody != "" {
+		lines = append(lines, "Response body: "+b.ResponseBody)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Curl returns an example curl command for the binding. Path parameters are
+// left as placeholders, e.g. "{name}".
+func (b *HTTPBinding) Curl() string {
+	path := pathVarRe.ReplaceAllString(b.Path, "{$1}")
+	cmd := "curl -X " + b.Method + ` "$HOST` + path + `"`
+	if b.ExampleBody == "" {
+		return cmd
+	}
+	// Single quotes can't be escaped within single quotes: close the quotes,
+	// add an escaped quote and reopen them.
+	body := strings.ReplaceAll(b.ExampleBody, "'", `'\''`)
+	return cmd + ` -H "Content-Type: application/json" -d '` + body + `'`
+}
+
+// pathVarRe matches the variables in the path templates, e.g. "{name}" or
+// "{name=servers/*}".
+var pathVarRe = regexp.MustCompile(`\{([^}=]+)(?:=[^}]*)?\}`)
+
+// optionsResolver resolves the extensions in the options: custom options
+// defined in the registry, and the ones compiled into protodoc, e.g.
+// google.api.http.
+type optionsResolver struct {
+	types *dynamicpb.Types
+}
+
+// newOptionsResolver returns a resolver for the extensions in the registry.
+// Building it walks the registry, so it should be reused across descriptors.
+func newOptionsResolver() optionsResolver {
+	return optionsResolver{dynamicpb.NewTypes(Files)}
+}
+
+func (r optionsResolver) FindExtensionByName(name protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	if xt, err := r.types.FindExtensionByName(name); err == nil {
+		return xt, nil
+	}
+	return protoregistry.GlobalTypes.FindExtensionByName(name)
+}
+
+func (r optionsResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	if xt, err := r.types.FindExtensionByNumber(message, field); err == nil {
+		return xt, nil
+	}
+	return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+}
+
+// resolvedOptions returns the options of the descriptor, with the custom
+// options resolved. It returns nil if there are no options.
+func resolvedOptions(d protoreflect.Descriptor, r optionsResolver) protoreflect.Message {
+	b, err := proto.Marshal(d.Options())
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+	opts := dynamicpb.NewMessage(d.Options().ProtoReflect().Descriptor())
+	if err := (proto.UnmarshalOptions{Resolver: r}).Unmarshal(b, opts); err != nil {
+		return nil
+	}
+	return opts
+}
+
+// httpRule returns the google.api.http option of the method, nil if it
+// doesn't have one.
+func httpRule(md protoreflect.MethodDescriptor, r optionsResolver) *annotations.HttpRule {
+	opts := resolvedOptions(md, r)
+	if opts == nil {
+		return nil
+	}
+
+	var rule *annotations.HttpRule
+	opts.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.FullName() != annotations.E_Http.TypeDescriptor().FullName() {
+			return true
+		}
+		// Option may be a dynamic message, if its definition comes from the
+		// registry.
+		b, err := proto.Marshal(v.Message().Interface())
+		if err != nil {
+			return false
+		}
+		rule = &annotations.HttpRule{}
+		if err := proto.Unmarshal(b, rule); err != nil {
+			rule = nil
+		}
+		return false
+	})
+	return rule
+}
+
+// scalarWKTs are the well-known types that map to scalars in JSON, and so can
+// be query parameters.
+var scalarWKTs = map[protoreflect.FullName]bool{
+	"google.protobuf.DoubleValue": true,
+	"google.protobuf.FloatValue":  true,
+	"google.protobuf.Int64Value":  true,
+	"google.protobuf.UInt64Value": true,
+	"google.protobuf.Int32Value":  true,
+	"google.protobuf.UInt32Value": true,
+	"google.protobuf.BoolValue":   true,
+	"google.protobuf.StringValue": true,
+	"google.protobuf.BytesValue":  true,
+	"google.protobuf.Duration":    true,
+	"google.protobuf.Timestamp":   true,
+	"google.protobuf.FieldMask":   true,
+}
+
+// queryParams returns the paths of the request fields that become query
+// parameters: fields not bound by the path or the body. Repeated messages,
+// maps and the other well-known types, e.g. google.protobuf.Struct, can't be
+// query parameters.
+func queryParams(md protoreflect.MessageDescriptor, prefix string, exclude map[string]bool, seen map[protoreflect.FullName]bool) []string {
+	seen[md.FullName()] = true
+	defer delete(seen, md.FullName())
+
+	var params []string
+	for i := 0; i < md.Fields().Len(); i++ {
+		fld := md.Fields().Get(i)
+		path := prefix + string(fld.Name())
+		if exclude[path] {
+			continue
+		}
+		if fld.Message() == nil || scalarWKTs[fld.Message().FullName()] {
+			params = append(params, path)
+			continue
+		}
+		if fld.IsList() || fld.IsMap() || seen[fld.Message().FullName()] || fld.Message().ParentFile().Package() == "google.protobuf" {
+			continue
+		}
+		params = append(params, queryParams(fld.Message(), path+".", exclude, seen)...)
+	}
+	return params
+}
+
+// removeSampleFields removes the fields with the given paths from the
+// sample value.
+func removeSampleFields(v *sampleValue, paths []string) {
+	for _, path := range paths {
+		name, rest, nested := strings.Cut(path, ".")
+		for i, sf := range v.fields {
+			if string(sf.fd.Name()) != name {
+				continue
+			}
+			if !nested {
+				v.fields = append(v.fields[:i], v.fields[i+1:]...)
+			} else if sf.values[0].msg {
+				removeSampleFields(sf.values[0], []string{rest})
+			}
+			break
+		}
+	}
+}
+
+// exampleBody returns the example request body for the binding, in the JSON
+// form.
+func exampleBody(md protoreflect.MessageDescriptor, b *HTTPBinding) string {
+	g := &sampleGenerator{f: Formatter{}.WithJSON(true), path: map[protoreflect.FullName]bool{}}
+	v := g.message(md)
+
+	var out strings.Builder
+	if b.Body == "*" {
+		removeSampleFields(v, b.PathParams)
+		writeJSONValue(&out, v, "")
+		return out.String()
+	}
+
+	for _, sf := range v.fields {
+		if string(sf.fd.Name()) == b.Body {
+			writeJSONFieldValue(&out, sf, "")
+			break
+		}
+	}
+	return out.String()
+}
+
+func httpBinding(md protoreflect.MethodDescriptor, rule *annotations.HttpRule) *HTTPBinding {
+	b := &HTTPBinding{Body: rule.GetBody(), ResponseBody: rule.GetResponseBody()}
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		b.Method, b.Path = "GET", p.Get
+	case *annotations.HttpRule_Put:
+		b.Method, b.Path = "PUT", p.Put
+	case *annotations.HttpRule_Post:
+		b.Method, b.Path = "POST", p.Post
+	case *annotations.HttpRule_Delete:
+		b.Method, b.Path = "DELETE", p.Delete
+	case *annotations.HttpRule_Patch:
+		b.Method, b.Path = "PATCH", p.Patch
+	case *annotations.HttpRule_Custom:
+		b.Method, b.Path = p.Custom.GetKind(), p.Custom.GetPath()
+	default:
+		return nil
+	}
+
+	exclude := map[string]bool{}
+	for _, m := range pathVarRe.FindAllStringSubmatch(b.Path, -1) {
+		b.PathParams = append(b.PathParams, m[1])
+		exclude[m[1]] = true
+	}
+
+	if b.Body != "*" {
+		if b.Body != "" {
+			exclude[b.Body] = true
+		}
+		b.QueryParams = queryParams(md.Input(), "", exclude, map[protoreflect.FullName]bool{})
+	}
+	if b.Body != "" {
+		b.ExampleBody = exampleBody(md.Input(), b)
+	}
+	return b
+}
+
+// httpBindings returns the HTTP bindings of the method: the one specified by
+// its google.api.http option, followed by the additional bindings.
+func httpBindings(md protoreflect.MethodDescriptor, r optionsResolver) []*HTTPBinding {
+	rule := httpRule(md, r)
+	if rule == nil {
+		return nil
+	}
+
+	var bindings []*HTTPBinding
+	for _, r := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+		if b := httpBinding(md, r); b != nil {
+			bindings = append(bindings, b)
+		}
+	}
+	return bindings
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestHTTPBindings(t *testing.T) {
+	d, err := Files.FindDescriptorByName("acme.api.ServerService")
+	assert.NoError(t, err)
+	methods := d.(protoreflect.ServiceDescriptor).Methods()
+
+	tests := []struct {
+		method string
+		want   []*HTTPBinding
+		curl   []string
+	}{
+		{
+			method: "GetStatus",
+			want: []*HTTPBinding{
+				{
+					Method:     "GET",
+					Path:       "/v1/{server=servers/*}/status",
+					PathParams: []string{"server"},
+				},
+				{
+					Method:      "GET",
+					Path:        "/v1/status",
+					QueryParams: []string{"server"},
+				},
+			},
+			curl: []string{
+				`curl -X GET "$HOST/v1/{server}/status"`,
+				`curl -X GET "$HOST/v1/status"`,
+			},
+		},
+		{
+			method: "SetLabels",
+			want: []*HTTPBinding{
+				{
+					Method:      "PATCH",
+					Path:        "/v1/{server=servers/*}/labels",
+					Body:        "labels",
+					PathParams:  []string{"server"},
+					QueryParams: []string{"validate_only"},
+					ExampleBody: "{\n  \"values\": {\n    \"key\": \"\"\n  }\n}",
+				},
+			},
+			curl: []string{
+				`curl -X PATCH "$HOST/v1/{server}/labels" -H "Content-Type: application/json" -d '{
+  "values": {
+    "key": ""
+  }
+}'`,
+			},
+		},
+		{
+			method: "TailLogs",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method, func(t *testing.T) {
+			bindings := httpBindings(methods.ByName(protoreflect.Name(test.method)), newOptionsResolver())
+			assert.Equal(t, test.want, bindings)
+
+			var curl []string
+			for _, b := range bindings {
+				curl = append(curl, b.Curl())
+			}
+			assert.Equal(t, test.curl, curl)
+		})
+	}
+
+	assert.Equal(t, "PATCH /v1/{server=servers/*}/labels\nPath parameters: server\nQuery parameters: validate_only\nBody: labels", httpBindings(methods.ByName("SetLabels"), newOptionsResolver())[0].Text())
+}
+
+func TestQueryParamsWellKnownTypes(t *testing.T) {
+	withTestProtos(t, map[string]string{
+		"test.proto": `
+syntax = "proto3";
+package test;
+
+import "google/protobuf/duration.proto";
+import "google/protobuf/empty.proto";
+import "google/protobuf/field_mask.proto";
+import "google/protobuf/struct.proto";
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/wrappers.proto";
+
+message Request {
+  string name = 1;
+  google.protobuf.Int32Value page_size = 2;
+  google.protobuf.Duration timeout = 3;
+  google.protobuf.Timestamp since = 4;
+  google.protobuf.FieldMask mask = 5;
+  google.protobuf.Struct attrs = 6;
+  google.protobuf.ListValue values = 7;
+  google.protobuf.Empty nothing = 8;
+}
+`,
+	})
+
+	d, err := Files.FindDescriptorByName("test.Request")
+	assert.NoError(t, err)
+	params := queryParams(d.(protoreflect.MessageDescriptor), "", map[string]bool{"name": true}, map[protoreflect.FullName]bool{})
+	assert.Equal(t, []string{"page_size", "timeout", "since", "mask"}, params)
+}
+
+func TestCurlQuotes(t *testing.T) {
+	b := &HTTPBinding{Method: "POST", Path: "/v1/notes", ExampleBody: `{"text": "it's"}`}
+	assert.Equal(t, `curl -X POST "$HOST/v1/notes" -H "Content-Type: application/json" -d '{"text": "it'\''s"}'`, b.Curl())
+}
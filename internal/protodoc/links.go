@@ -0,0 +1,113 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// ExternalLink maps types documented elsewhere to their documentation URL.
+// Pattern is either a full type name, e.g. google.type.LatLng, or a package
+// prefix ending with ".*", e.g. google.protobuf.*. URL template is executed
+// with the following fields:
+//
+//	.FullName: Full name of the type, e.g. google.protobuf.Duration
+//	.Name:     Short name of the type, e.g. Duration
+//	.Package:  Package of the type, e.g. google.protobuf
+type ExternalLink struct {
+	Pattern string
+	tmpl    *template.Template
+}
+
+type externalLinkData struct {
+	FullName string
+	Name     string
+	Package  string
+}
+
+// NewExternalLink creates a new external link for the given pattern and
+// URL template.
+func NewExternalLink(pattern, urlTmpl string) (*ExternalLink, error) {
+	if pattern == "" || urlTmpl == "" {
+		return nil, fmt.Errorf("external link pattern and URL template should not be empty")
+	}
+	tmpl, err := template.New(pattern).Option("missingkey=error").Parse(urlTmpl)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing URL template for %s: %v", pattern, err)
+	}
+	return &ExternalLink{Pattern: pattern, tmpl: tmpl}, nil
+}
+
+// ParseExternalLink parses an external link specified as
+// "<pattern>=<url_template>", e.g.
+// "google.protobuf.*=https://protobuf.dev/reference/protobuf/google.protobuf/#{{.Name}}".
+func ParseExternalLink(s string) (*ExternalLink, error) {
+	pattern, urlTmpl, ok := strings.Cut(s, "=")
+	if !ok {
+		return nil, fmt.Errorf("invalid external link %s, should be of the form <pattern>=<url_template>", s)
+	}
+	return NewExternalLink(strings.TrimSpace(pattern), strings.TrimSpace(urlTmpl))
+}
+
+// matchPattern matches kind against a pattern, which is either a full name or
+// a package prefix ending with ".*". It returns the length of the matched
+// pattern, or -1 if kind doesn't match the pattern.
+func matchPattern(pattern, kind string) int {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		if strings.HasPrefix(kind, prefix) {
+			return len(prefix)
+		}
+		return -1
+	}
+	if kind == pattern {
+		return len(kind)
+	}
+	return -1
+}
+
+func (el *ExternalLink) url(kind string) (string, error) {
+	data := externalLinkData{
+		FullName: kind,
+		Name:     kind[strings.LastIndex(kind, ".")+1:],
+		Package:  packageOf(kind),
+	}
+	var b strings.Builder
+	if err := el.tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// externalURL returns the URL for the given kind from the longest matching
+// external link, or an empty string if no external link matches.
+func (f Formatter) externalURL(kind string) string {
+	var match *ExternalLink
+	maxLen := -1
+	for _, el := range f.externalLinks {
+		if n := matchPattern(el.Pattern, kind); n > maxLen {
+			match, maxLen = el, n
+		}
+	}
+	if match == nil {
+		return ""
+	}
+	url, err := match.url(kind)
+	if err != nil {
+		return ""
+	}
+	return url
+}
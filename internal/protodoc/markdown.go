@@ -0,0 +1,241 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"io"
+	"strings"
+	"text/template"
+)
+
+var MarkdownTmpl = `# {{ .Title }}
+{{ range .Services }}
+<a id="{{ .Anchor }}"></a>
+## {{ .Name }}
+{{ if .Comment }}
+{{ .Comment }}
+{{ end }}
+` + "```" + `protobuf
+{{ .Body }}
+` + "```" + `
+{{- if .Links }}
+
+Linked types:
+{{ range .Links }}
+- [{{ .Kind }}]({{ .URL }})
+{{- end }}
+{{- end }}
+{{ range .HTTP }}
+### {{ .Name }} over HTTP
+{{ range .Bindings }}
+` + "```" + `
+{{ .Text }}
+` + "```" + `
+{{- if .ExampleBody }}
+
+` + "```" + `sh
+{{ .Curl }}
+` + "```" + `
+{{- end }}
+{{ end }}
+{{- end }}
+{{- end -}}
+{{ range .Msgs }}
+{{ if .Name -}}
+<a id="{{ .Anchor }}"></a>
+## {{ .Name }}
+
+{{ end -}}
+{{ if .Comment -}}
+{{ .Comment }}
+
+{{ end -}}
+` + "```" + `{{ $.Lang }}
+{{ if .Body }}{{ .Body }}
+{{ end -}}
+` + "```" + `
+{{- if .Links }}
+
+Linked types:
+{{ range .Links }}
+- [{{ .Kind }}]({{ .URL }})
+{{- end }}
+{{- end }}
+{{ end -}}
+{{ range .Enums }}
+<a id="{{ .Anchor }}"></a>
+## {{ .Name }}
+{{ if .Comment }}
+{{ .Comment }}
+{{ end }}
+{{- if .Deprecated }}
+Deprecated.
+{{ end }}
+` + "```" + `protobuf
+{{ .Body }}
+` + "```" + `
+{{ end -}}
+`
+
+var markdownTmpl = template.Must(template.New("markdown").Parse(MarkdownTmpl))
+
+type mdLink struct {
+	Kind string
+	URL  string
+}
+
+type mdMsg struct {
+	Name    string
+	Anchor  string
+	Comment string
+	Body    string
+	Links   []mdLink
+}
+
+type mdService struct {
+	Name    string
+	Anchor  string
+	Comment string
+	Body    string
+	Links   []mdLink
+	HTTP    []*mdHTTP
+}
+
+// mdHTTP is the HTTP bindings of a method.
+type mdHTTP struct {
+	Name     string
+	Bindings []*HTTPBinding
+}
+
+type mdEnum struct {
+	Name       string
+	Anchor     string
+	Comment    string
+	Deprecated bool
+	Body       string
+}
+
+type mdPage struct {
+	Title    string
+	Lang     string
+	Services []*mdService
+	Msgs     []*mdMsg
+	Enums    []*mdEnum
+}
+
+// markdownRenderer renders pages as Markdown files, with config in fenced
+// code blocks followed by the links to the types used in them.
+type markdownRenderer struct {
+	format string
+
+	// Whether the pages are compiled as MDX, e.g. by Docusaurus.
+	mdx bool
+}
+
+// mdxEscaper escapes the characters starting JSX tags and expressions in MDX.
+var mdxEscaper = strings.NewReplacer("<", `\<`, ">", `\>`, "{", `\{`, "}", `\}`)
+
+// prose returns the comment to be written outside the code blocks.
+func (r *markdownRenderer) prose(comment string) string {
+	if r.mdx {
+		return mdxEscaper.Replace(comment)
+	}
+	return comment
+}
+
+// markdownLangs are the code block languages for the formats.
+var markdownLangs = map[string]string{
+	"yaml":   "yaml",
+	"textpb": "textproto",
+	"json":   "jsonc",
+}
+
+func (r *markdownRenderer) pagePath(page *Page) string {
+	return page.Name + ".md"
+}
+
+func (r *markdownRenderer) Render(pages []*Page) ([]*File, error) {
+	return renderPages(pages, r.pagePath, r.renderPage)
+}
+
+// tokenToMarkdown returns the token's line in the code block. Code blocks
+// are not HTML, so the line is built from the plain text fields.
+func tokenToMarkdown(tok *Token) string {
+	var b strings.Builder
+	if tok.Comment != "" {
+		b.WriteString(tok.Comment + "\n")
+	}
+	b.WriteString(tok.Prefix + tok.Text)
+	if tok.Kind != "" {
+		b.WriteString(tok.Sep + "<" + tok.Kind + ">")
+	}
+	b.WriteString(string(tok.Suffix))
+	b.WriteString(tok.ExtraLine)
+	return b.String()
+}
+
+// tokenLink returns the link to the documentation of the token's type, if
+// any, listed after the code block.
+func tokenLink(tok *Token) (mdLink, bool) {
+	if tok.URL == "" {
+		return mdLink{}, false
+	}
+	return mdLink{Kind: tok.linkedKind(), URL: tok.URL}, true
+}
+
+func (r *markdownRenderer) renderPage(w io.Writer, page *Page) error {
+	mp := &mdPage{Title: pageTitle(page), Lang: markdownLangs[r.format]}
+
+	for _, svc := range page.Services {
+		ms := &mdService{Name: svc.Name, Anchor: svc.Anchor(), Comment: r.prose(svc.Comment)}
+		seen := map[string]bool{}
+		var methods []string
+		for _, m := range svc.Methods {
+			methods = append(methods, methodProto(m))
+			if len(m.HTTP) != 0 {
+				ms.HTTP = append(ms.HTTP, &mdHTTP{Name: m.Name, Bindings: m.HTTP})
+			}
+			for _, link := range []mdLink{{m.Request, m.RequestURL}, {m.Response, m.ResponseURL}} {
+				if link.URL != "" && !seen[link.Kind] {
+					seen[link.Kind] = true
+					ms.Links = append(ms.Links, link)
+				}
+			}
+		}
+		ms.Body = strings.Join(methods, "\n\n")
+		mp.Services = append(mp.Services, ms)
+	}
+
+	for _, mt := range page.Msgs {
+		m := &mdMsg{Name: mt.Name, Anchor: mt.Anchor(), Comment: r.prose(mt.Comment)}
+		seen := map[string]bool{}
+		var lines []string
+		for _, tok := range mt.Tokens {
+			lines = append(lines, tokenToMarkdown(tok))
+			if link, ok := tokenLink(tok); ok && !seen[link.Kind] {
+				seen[link.Kind] = true
+				m.Links = append(m.Links, link)
+			}
+		}
+		m.Body = strings.TrimRight(strings.Join(lines, "\n"), "\n")
+		mp.Msgs = append(mp.Msgs, m)
+	}
+
+	for _, e := range page.Enums {
+		mp.Enums = append(mp.Enums, &mdEnum{Name: e.Name, Anchor: e.Anchor(), Comment: r.prose(e.Comment), Deprecated: e.Deprecated, Body: enumProto(e)})
+	}
+
+	return markdownTmpl.Execute(w, mp)
+}
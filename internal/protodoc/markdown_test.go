@@ -0,0 +1,69 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestMarkdownRenderer(t *testing.T) {
+	c := &Config{Output: OutputMarkdown, Format: "textpb"}
+	assert.NoError(t, c.Validate())
+	f := c.Formatter()
+
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"cloudprober.probes.ProbeDef"}), nil, f, nil)
+	assert.NoError(t, err)
+
+	files, err := c.Renderer().Render(pages)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Equal(t, "overview.md", files[0].Path)
+	assert.Equal(t, "probes.md", files[1].Path)
+
+	out := string(files[0].Content)
+	assert.Contains(t, out, "# Overview\n\nNext tag: 101\n\n```textproto\nname: <string>\n")
+	assert.Contains(t, out, "# Define one probe type\n# Only one of: http_probe, dns_probe, user_defined_probe.\nhttp_probe <cloudprober.probes.http.ProbeConf> {\n")
+	assert.Contains(t, out, "# Extension numbers: 200 to max.\n# UDP probe, added as an extension.\n[cloudprober.probes.udp.udp_probe] <cloudprober.probes.udp.ProbeConf> {\n")
+	assert.Contains(t, out, "- [cloudprober.probes.http.ProbeConf](probes.md#cloudprober_probes_http_ProbeConf)\n")
+
+	assert.Contains(t, string(files[1].Content), `# probes
+
+<a id="cloudprober_probes_AdditionalLabel"></a>
+## cloudprober.probes.AdditionalLabel
+
+`+"```textproto\nkey: <string>\n")
+
+	_, err = NewRenderer("pdf", f)
+	assert.Error(t, err)
+}
+
+func TestTokenToMarkdown(t *testing.T) {
+	// Lines are built from the plain text fields, not from the HTML ones.
+	toks := ProcessTokensForHTML([]*Token{
+		{Comment: "# Labels, e.g. a & b.", Kind: "string", Text: "labels", Default: `"<none>"`},
+		{Kind: "acme.config.Backend", Text: "backend", TextHTML: "<b>ignored</b>"},
+	}, Formatter{})
+	assert.Equal(t, "# Labels, e.g. a & b.\nlabels: <string> | default: \"<none>\"\n", tokenToMarkdown(toks[0]))
+	assert.Equal(t, "backend: <acme.config.Backend>\n", tokenToMarkdown(toks[1]))
+
+	_, ok := tokenLink(toks[0])
+	assert.False(t, ok)
+	link, ok := tokenLink(toks[1])
+	assert.True(t, ok)
+	assert.Equal(t, mdLink{Kind: "acme.config.Backend", URL: "acme.config#acme_config_Backend"}, link)
+}
@@ -0,0 +1,355 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ModelVersion is the version of the documentation model. It's incremented
+// on incompatible changes to the model.
+const ModelVersion = 1
+
+// ModelFile is the name of the file the documentation model is written to.
+const ModelFile = "protodoc.json"
+
+// Model is the resolved documentation model: pages and the messages,
+// services and enums documented on them.
+type Model struct {
+	Version  int             `json:"version"`
+	Pages    []*ModelPage    `json:"pages"`
+	Messages []*ModelMessage `json:"messages"`
+	Services []*ModelService `json:"services,omitempty"`
+	Enums    []*ModelEnum    `json:"enums,omitempty"`
+}
+
+// ModelPage is a documentation page.
+type ModelPage struct {
+	Name  string `json:"name"`
+	Title string `json:"title,omitempty"`
+
+	// Root message, for the root pages.
+	Root string `json:"root,omitempty"`
+
+	// Messages documented on this page, in order.
+	Messages []string `json:"messages"`
+
+	// Services documented on this page, in order.
+	Services []string `json:"services,omitempty"`
+
+	// Enums documented on this page, in order.
+	Enums []string `json:"enums,omitempty"`
+}
+
+// ModelMessage is a documented message.
+type ModelMessage struct {
+	Name    string        `json:"name"`
+	Page    string        `json:"page"`
+	URL     string        `json:"url"`
+	Comment string        `json:"comment,omitempty"`
+	Fields  []*ModelField `json:"fields"`
+
+	// Extensions of the message found in the registry, and the declared
+	// extension ranges, e.g. "200 to max".
+	Extensions      []*ModelField `json:"extensions,omitempty"`
+	ExtensionRanges []string      `json:"extension_ranges,omitempty"`
+}
+
+// ModelField is a field of a documented message.
+type ModelField struct {
+	// Name of the field, or the full name for the extensions.
+	Name     string `json:"name"`
+	JSONName string `json:"json_name"`
+	Number   int32  `json:"number"`
+
+	// Label is one of optional, required or repeated.
+	Label string `json:"label"`
+
+	// Kind is the scalar type name (e.g. string), the full name of the
+	// message or enum type, or the map kind, e.g. "map<string, acme.Foo>".
+	Kind string `json:"kind"`
+
+	Default string `json:"default,omitempty"`
+	Comment string `json:"comment,omitempty"`
+
+	// Link to the documentation of the field's type, or of the value type
+	// for the maps.
+	URL string `json:"url,omitempty"`
+
+	// Oneof the field belongs to.
+	Oneof string `json:"oneof,omitempty"`
+
+	// Values of the enum fields.
+	EnumValues []string `json:"enum_values,omitempty"`
+}
+
+// ModelService is a documented gRPC service.
+type ModelService struct {
+	Name    string         `json:"name"`
+	Page    string         `json:"page"`
+	URL     string         `json:"url"`
+	Comment string         `json:"comment,omitempty"`
+	Methods []*ModelMethod `json:"methods"`
+}
+
+// ModelMethod is an RPC of a documented service.
+type ModelMethod struct {
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	Comment string `json:"comment,omitempty"`
+
+	// Full names of the request and response messages, and the links to
+	// their documentation.
+	Request     string `json:"request"`
+	RequestURL  string `json:"request_url,omitempty"`
+	Response    string `json:"response"`
+	ResponseURL string `json:"response_url,omitempty"`
+
+	ClientStreaming bool `json:"client_streaming,omitempty"`
+	ServerStreaming bool `json:"server_streaming,omitempty"`
+
+	// Method options, as they appear in the proto, e.g. "deprecated = true".
+	Options []string `json:"options,omitempty"`
+
+	// HTTP bindings, from the google.api.http option.
+	HTTP []*HTTPBinding `json:"http,omitempty"`
+}
+
+// ModelEnum is a documented enum.
+type ModelEnum struct {
+	Name       string            `json:"name"`
+	Page       string            `json:"page"`
+	URL        string            `json:"url"`
+	Comment    string            `json:"comment,omitempty"`
+	Deprecated bool              `json:"deprecated,omitempty"`
+	Values     []*ModelEnumValue `json:"values"`
+}
+
+// ModelEnumValue is a value of a documented enum.
+type ModelEnumValue struct {
+	Name       string `json:"name"`
+	Number     int32  `json:"number"`
+	Comment    string `json:"comment,omitempty"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+}
+
+func modelEnum(e *EnumDoc, page string, f Formatter) *ModelEnum {
+	me := &ModelEnum{
+		Name:       e.Name,
+		Page:       page,
+		URL:        kindToURL(e.Name, f),
+		Comment:    e.Comment,
+		Deprecated: e.Deprecated,
+		Values:     []*ModelEnumValue{},
+	}
+	for _, v := range e.Values {
+		me.Values = append(me.Values, &ModelEnumValue{Name: v.Name, Number: v.Number, Comment: v.Comment, Deprecated: v.Deprecated})
+	}
+	return me
+}
+
+func modelService(svc *ServiceDoc, page string, f Formatter) *ModelService {
+	pageURL := f.docURL(page + f.pageExt)
+	ms := &ModelService{
+		Name:    svc.Name,
+		Page:    page,
+		URL:     pageURL + "#" + svc.Anchor(),
+		Comment: svc.Comment,
+		Methods: []*ModelMethod{},
+	}
+	for _, m := range svc.Methods {
+		ms.Methods = append(ms.Methods, &ModelMethod{
+			Name:            m.Name,
+			URL:             pageURL + "#" + m.Anchor(),
+			Comment:         m.Comment,
+			Request:         m.Request,
+			RequestURL:      m.RequestURL,
+			Response:        m.Response,
+			ResponseURL:     m.ResponseURL,
+			ClientStreaming: m.ClientStreaming,
+			ServerStreaming: m.ServerStreaming,
+			Options:         m.Options,
+			HTTP:            m.HTTP,
+		})
+	}
+	return ms
+}
+
+func fieldKind(fld protoreflect.FieldDescriptor) string {
+	switch fld.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return string(fld.Message().FullName())
+	case protoreflect.EnumKind:
+		return string(fld.Enum().FullName())
+	}
+	return fld.Kind().String()
+}
+
+func modelField(fld protoreflect.FieldDescriptor, f Formatter) *ModelField {
+	mf := &ModelField{
+		Name:     string(fld.Name()),
+		JSONName: fld.JSONName(),
+		Number:   int32(fld.Number()),
+		Label:    fld.Cardinality().String(),
+		Kind:     fieldKind(fld),
+		Comment:  plainComment(fld),
+	}
+	// Extensions are referred to by their full names, also in JSON.
+	if fld.IsExtension() {
+		mf.Name = string(fld.FullName())
+		mf.JSONName = "[" + mf.Name + "]"
+	}
+	if fld.HasDefault() {
+		mf.Default = fld.Default().String()
+		if ed := fld.DefaultEnumValue(); ed != nil {
+			mf.Default = string(ed.Name())
+		}
+	}
+	if fld.Message() != nil || fld.Enum() != nil {
+		mf.URL = kindToURL(mf.Kind, f)
+	}
+	if fld.IsMap() {
+		mf.Kind = mapKind(fld)
+		mf.URL = kindToURL(fieldKind(fld.MapValue()), f)
+	}
+	if oo := fld.ContainingOneof(); oo != nil && !oo.IsSynthetic() {
+		mf.Oneof = string(oo.Name())
+	}
+	if ed := fld.Enum(); ed != nil {
+		for i := 0; i < ed.Values().Len(); i++ {
+			mf.EnumValues = append(mf.EnumValues, string(ed.Values().Get(i).Name()))
+		}
+	}
+	return mf
+}
+
+func modelMessage(name protoreflect.FullName, page string, url string, f Formatter) (*ModelMessage, error) {
+	md, err := findMessage(name)
+	if err != nil {
+		return nil, err
+	}
+	mm := &ModelMessage{
+		Name:    string(name),
+		Page:    page,
+		URL:     url,
+		Comment: plainComment(md),
+		Fields:  []*ModelField{},
+	}
+	for i := 0; i < md.Fields().Len(); i++ {
+		mm.Fields = append(mm.Fields, modelField(md.Fields().Get(i), f))
+	}
+	for _, xd := range findExtensions(md) {
+		mm.Extensions = append(mm.Extensions, modelField(xd, f))
+	}
+	mm.ExtensionRanges = extensionRanges(md)
+	return mm, nil
+}
+
+// BuildModel builds the documentation model for the given pages. Formatter
+// should be the one used to generate the pages.
+func BuildModel(pages []*Page, f Formatter) (*Model, error) {
+	documented := map[string]bool{}
+	for _, page := range pages {
+		for _, msg := range page.Msgs {
+			if msg.Name != "" {
+				documented[msg.Name] = true
+			}
+		}
+		for _, e := range page.Enums {
+			documented[e.Name] = true
+		}
+	}
+	f = f.withDocumented(documented)
+
+	m := &Model{Version: ModelVersion}
+
+	// Root messages can also be documented on the package pages, e.g. if
+	// they are referenced by other messages. They are listed once, as the
+	// root messages, the root pages coming first.
+	seen := map[string]bool{}
+	addMessage := func(mm *ModelMessage) {
+		if !seen[mm.Name] {
+			seen[mm.Name] = true
+			m.Messages = append(m.Messages, mm)
+		}
+	}
+
+	for _, page := range pages {
+		mp := &ModelPage{
+			Name:     page.Name,
+			Title:    page.Title,
+			Root:     string(page.Root),
+			Messages: []string{},
+		}
+
+		if page.Root != "" {
+			mm, err := modelMessage(page.Root, page.Name, f.docURL(page.Name+f.pageExt), f)
+			if err != nil {
+				return nil, err
+			}
+			mp.Messages = append(mp.Messages, mm.Name)
+			addMessage(mm)
+		}
+
+		for _, msg := range page.Msgs {
+			if msg.Name == "" {
+				continue
+			}
+			mm, err := modelMessage(protoreflect.FullName(msg.Name), page.Name, kindToURL(msg.Name, f), f)
+			if err != nil {
+				return nil, err
+			}
+			mp.Messages = append(mp.Messages, mm.Name)
+			addMessage(mm)
+		}
+
+		for _, svc := range page.Services {
+			mp.Services = append(mp.Services, svc.Name)
+			m.Services = append(m.Services, modelService(svc, page.Name, f))
+		}
+
+		for _, e := range page.Enums {
+			mp.Enums = append(mp.Enums, e.Name)
+			m.Enums = append(m.Enums, modelEnum(e, page.Name, f))
+		}
+		m.Pages = append(m.Pages, mp)
+	}
+
+	sort.SliceStable(m.Messages, func(i, j int) bool { return m.Messages[i].Name < m.Messages[j].Name })
+	sort.SliceStable(m.Services, func(i, j int) bool { return m.Services[i].Name < m.Services[j].Name })
+	sort.SliceStable(m.Enums, func(i, j int) bool { return m.Enums[i].Name < m.Enums[j].Name })
+	return m, nil
+}
+
+// jsonRenderer renders the documentation model as a JSON document.
+type jsonRenderer struct {
+	f Formatter
+}
+
+func (r *jsonRenderer) Render(pages []*Page) ([]*File, error) {
+	m, err := BuildModel(pages, r.f)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling documentation model: %v", err)
+	}
+	return []*File{{Path: ModelFile, Content: append(b, '\n')}}, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestJSONRenderer(t *testing.T) {
+	c := &Config{Output: OutputJSON, Grouping: GroupingConfig{Root: "acme"}}
+	assert.NoError(t, c.Validate())
+	f := c.Formatter()
+
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"acme.config.ServerConfig"}), nil, f, nil)
+	assert.NoError(t, err)
+
+	files, err := c.Renderer().Render(pages)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, ModelFile, files[0].Path)
+
+	m := &Model{}
+	assert.NoError(t, json.Unmarshal(files[0].Content, m))
+	assert.Equal(t, ModelVersion, m.Version)
+
+	assert.Equal(t, "overview", m.Pages[0].Name)
+	assert.Equal(t, "acme.config.ServerConfig", m.Pages[0].Root)
+	assert.Equal(t, []string{"acme.config.ServerConfig"}, m.Pages[0].Messages)
+
+	msgs := map[string]*ModelMessage{}
+	for _, mm := range m.Messages {
+		msgs[mm.Name] = mm
+	}
+
+	sc := msgs["acme.config.ServerConfig"]
+	assert.Equal(t, "overview", sc.URL)
+	assert.Equal(t, "Server configuration.", sc.Comment)
+	assert.Equal(t, &ModelField{
+		Name:     "listen_addr",
+		JSONName: "listenAddr",
+		Number:   1,
+		Label:    "optional",
+		Kind:     "string",
+		Default:  ":8080",
+		Comment:  "Address to listen on.",
+	}, sc.Fields[0])
+	assert.Equal(t, "ERROR", sc.Fields[3].Default)
+	assert.Equal(t, []string{"ERROR", "DEBUG", "VERBOSE"}, sc.Fields[3].EnumValues)
+	assert.Equal(t, "config#acme_config_ServerConfig_LogLevel", sc.Fields[3].URL)
+
+	assert.Len(t, m.Enums, 1)
+	assert.Equal(t, "config", m.Enums[0].Page)
+	assert.Equal(t, &ModelEnumValue{Name: "VERBOSE", Number: 3, Comment: "Use DEBUG instead.", Deprecated: true}, m.Enums[0].Values[2])
+
+	h := msgs["acme.config.Handler"]
+	assert.Equal(t, "config", h.Page)
+	assert.Equal(t, "config#acme_config_Backend", h.Fields[1].URL)
+	assert.Equal(t, "handler", h.Fields[1].Oneof)
+
+	assert.Equal(t, "repeated", msgs["acme.config.Backend"].Fields[1].Label)
+}
+
+func TestBuildModelRootInPackage(t *testing.T) {
+	f := Formatter{}.WithGrouping(Grouping{Root: "acme"}).WithHomeURL("https://example.com/docs/")
+
+	// Backend refers to itself, so it's documented on the package page too.
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"acme.config.Backend"}), nil, f, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"overview", "config"}, pageNames(pages))
+
+	m, err := BuildModel(pages, f)
+	assert.NoError(t, err)
+	assert.Len(t, m.Messages, 1)
+	assert.Equal(t, "overview", m.Messages[0].Page)
+	assert.Equal(t, []string{"acme.config.Backend"}, m.Pages[1].Messages)
+
+	// Absolute home URL is kept as is.
+	assert.Equal(t, "https://example.com/docs/overview", m.Messages[0].URL)
+	assert.Equal(t, "https://example.com/docs/config#acme_config_Backend", m.Messages[0].Fields[1].URL)
+}
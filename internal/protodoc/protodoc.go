@@ -34,10 +34,20 @@ type Token struct {
 	URL     string
 	Default string
 
+	// HTML anchor of the field's line, set only for the fields of the
+	// documented messages (not of the inlined ones) in the primary format.
+	Anchor string
+
 	MessageHeader bool
 	yaml          bool
+	json          bool
+	repeated      bool
 	NoExtraLine   bool
 
+	// Type linked from the kind, if different from it, e.g. the value type
+	// of the maps.
+	linkKind string
+
 	// Filed by token processor
 	TextHTML  template.HTML
 	Sep       string
@@ -45,13 +55,38 @@ type Token struct {
 }
 
 type Formatter struct {
-	yaml    bool
+	yaml bool
+
+	// Whether to use the JSON syntax, as used by protojson. It takes
+	// precedence over yaml.
+	json bool
+
 	depth   int
 	prefix  string
 	relPath string
+	homeURL string
+
+	// Extension added to page names in links, e.g. ".md".
+	pageExt string
 
 	// Whether to use JSON names for YAML output.
 	jsonNamesForYAML bool
+
+	// How messages are grouped into pages, DefaultGrouping if nil.
+	grouping *Grouping
+
+	// Messages that are documented, and hence can be linked to. If nil, all
+	// messages in the registry are considered documented.
+	documented map[string]bool
+
+	// Links for the types documented elsewhere.
+	externalLinks []*ExternalLink
+
+	// Patterns for the messages that should not be documented.
+	excludes []string
+
+	// Patterns for the gRPC services to document.
+	services []string
 }
 
 func (f Formatter) WithYAML(yaml bool, jsonNames bool) Formatter {
@@ -61,6 +96,28 @@ func (f Formatter) WithYAML(yaml bool, jsonNames bool) Formatter {
 	return f2
 }
 
+// WithJSON returns a formatter for the JSON syntax, if json is true.
+func (f Formatter) WithJSON(json bool) Formatter {
+	f2 := f
+	f2.json = json
+	if json {
+		f2.yaml = false
+	}
+	return f2
+}
+
+// Format returns the config syntax of the formatter: "yaml", "textpb" or
+// "json".
+func (f Formatter) Format() string {
+	if f.json {
+		return "json"
+	}
+	if f.yaml {
+		return "yaml"
+	}
+	return "textpb"
+}
+
 func (f Formatter) WithDepth(depth int) Formatter {
 	f2 := f
 	f2.depth = depth
@@ -79,6 +136,84 @@ func (f Formatter) WithRelPath(relPath string) Formatter {
 	return f2
 }
 
+func (f Formatter) WithPageExt(pageExt string) Formatter {
+	f2 := f
+	f2.pageExt = pageExt
+	return f2
+}
+
+func (f Formatter) WithHomeURL(homeURL string) Formatter {
+	f2 := f
+	f2.homeURL = homeURL
+	return f2
+}
+
+func (f Formatter) WithGrouping(g Grouping) Formatter {
+	f2 := f
+	f2.grouping = &g
+	return f2
+}
+
+func (f Formatter) withDocumented(documented map[string]bool) Formatter {
+	f2 := f
+	f2.documented = documented
+	return f2
+}
+
+func (f Formatter) WithExternalLinks(links []*ExternalLink) Formatter {
+	f2 := f
+	f2.externalLinks = links
+	return f2
+}
+
+func (f Formatter) WithExcludes(patterns []string) Formatter {
+	f2 := f
+	f2.excludes = patterns
+	return f2
+}
+
+// WithServices returns a formatter documenting the gRPC services matching
+// the given patterns: full names or package prefixes ending with ".*".
+func (f Formatter) WithServices(patterns []string) Formatter {
+	f2 := f
+	f2.services = patterns
+	return f2
+}
+
+// useJSONNames returns whether the field JSON names are used for the config
+// keys.
+func (f Formatter) useJSONNames() bool {
+	return f.json || (f.yaml && f.jsonNamesForYAML)
+}
+
+// fieldName returns the field name as it appears in the config.
+func fieldName(fld protoreflect.FieldDescriptor, f Formatter) string {
+	// Extensions are referred to by their full names, in all the syntaxes.
+	// In YAML, unquoted brackets would make a flow sequence.
+	if fld.IsExtension() {
+		name := "[" + string(fld.FullName()) + "]"
+		if f.json || f.yaml {
+			return `"` + name + `"`
+		}
+		return name
+	}
+	if f.json {
+		return `"` + fld.JSONName() + `"`
+	}
+	if f.useJSONNames() {
+		return fld.JSONName()
+	}
+	return string(fld.Name())
+}
+
+// Grouping returns the grouping used by the formatter.
+func (f Formatter) Grouping() Grouping {
+	if f.grouping == nil {
+		return DefaultGrouping
+	}
+	return *f.grouping
+}
+
 func finalToken(fld protoreflect.FieldDescriptor, f Formatter, nocomment bool) *Token {
 	var comment string
 	if !nocomment {
@@ -86,24 +221,37 @@ func finalToken(fld protoreflect.FieldDescriptor, f Formatter, nocomment bool) *
 	}
 
 	kind := fld.Kind().String()
-	if fld.Kind() == protoreflect.MessageKind {
+	switch fld.Kind() {
+	case protoreflect.MessageKind:
 		kind = string(fld.Message().FullName())
+	case protoreflect.EnumKind:
+		// Enums are documented along with the messages, with their values.
+		kind = string(fld.Enum().FullName())
 	}
 
 	tok := &Token{
-		yaml:    f.yaml,
-		Prefix:  f.prefix,
-		Comment: comment,
-		Kind:    kind,
-		Text:    string(fld.Name()),
+		yaml: f.yaml,
+		json: f.json,
+		// Only JSON syntax marks the repeated fields, as arrays.
+		repeated: f.json && fld.IsList(),
+		Prefix:   f.prefix,
+		Comment:  comment,
+		Kind:     kind,
+		Text:     fieldName(fld, f),
 	}
 
-	if fld.HasDefault() {
-		tok.Default = fld.Default().String()
+	// Maps are shown as such, instead of the repeated map entries, and link
+	// to their value type.
+	if fld.IsMap() {
+		tok.Kind = mapKind(fld)
+		tok.linkKind = fieldKind(fld.MapValue())
 	}
 
-	if f.yaml && f.jsonNamesForYAML {
-		tok.Text = fld.JSONName()
+	if fld.HasDefault() {
+		tok.Default = fld.Default().String()
+		if ev := fld.DefaultEnumValue(); ev != nil {
+			tok.Default = string(ev.Name())
+		}
 	}
 
 	return tok
@@ -118,6 +266,14 @@ func dumpExtendedMsg(fld protoreflect.FieldDescriptor, f Formatter) ([]*Token, [
 	tok.MessageHeader = true
 	tok.NoExtraLine = true
 
+	// Inlined message's description follows the field's comment.
+	if msgComment := formatComment(fld.Message(), f); msgComment != "" {
+		if tok.Comment != "" {
+			tok.Comment += "\n"
+		}
+		tok.Comment += msgComment
+	}
+
 	lines = append(lines, tok)
 
 	newPrefix := f.prefix + "  "
@@ -125,7 +281,14 @@ func dumpExtendedMsg(fld protoreflect.FieldDescriptor, f Formatter) ([]*Token, [
 		newPrefix = f.prefix + "    "
 	}
 	toks, next := DumpMessage(fld.Message(), f.WithDepth(f.depth-1).WithPrefix(newPrefix))
-	if f.yaml && fld.Cardinality() == protoreflect.Repeated {
+	// In YAML, messages without fields are written as empty mappings.
+	if f.yaml && len(toks) == 0 {
+		text := "{}"
+		if fld.Cardinality() == protoreflect.Repeated {
+			text = "- {}"
+		}
+		toks = []*Token{{Prefix: f.prefix + "  ", Text: text}}
+	} else if f.yaml && fld.Cardinality() == protoreflect.Repeated {
 		toks[0].Prefix = f.prefix + "  - "
 	}
 	lines = append(lines, toks...)
@@ -133,55 +296,165 @@ func dumpExtendedMsg(fld protoreflect.FieldDescriptor, f Formatter) ([]*Token, [
 	// If it's not a yaml, add a "}" at the end and limit the line break before
 	// that to just one (default is 2).
 	if !f.yaml {
+		end := "}"
+		if f.json && fld.Cardinality() == protoreflect.Repeated {
+			end = "}]"
+		}
 		lines[len(lines)-1].NoExtraLine = true
-		lines = append(lines, &Token{Prefix: f.prefix, Text: "}"})
+		lines = append(lines, &Token{Prefix: f.prefix, Text: end})
 	}
 	nextMessageName = append(nextMessageName, next...)
 
 	return lines, nextMessageName
 }
 
+// mapKind returns the kind of the map field, e.g. "map<string, acme.Foo>".
+func mapKind(fld protoreflect.FieldDescriptor) string {
+	return "map<" + fieldKind(fld.MapKey()) + ", " + fieldKind(fld.MapValue()) + ">"
+}
+
+// dumpMap dumps the map field. If depth allows, maps with message values
+// are expanded in the config syntax: a mapping from the key to the value's
+// fields for YAML and JSON, entries with the key and value fields for
+// textproto.
+func dumpMap(fld protoreflect.FieldDescriptor, f Formatter) ([]*Token, []protoreflect.FullName) {
+	tok := finalToken(fld, f, false)
+
+	val := fld.MapValue()
+	if val.Message() == nil {
+		return []*Token{tok}, nil
+	}
+	next := []protoreflect.FullName{val.Message().FullName()}
+	if f.depth <= 1 || wktKind(val.Message(), f) != "" {
+		return []*Token{tok}, next
+	}
+
+	tok.MessageHeader = true
+	tok.NoExtraLine = true
+	lines := []*Token{tok}
+
+	keyPrefix := f.prefix + "  "
+	keyKind := fieldKind(fld.MapKey())
+	switch f.Format() {
+	case "yaml":
+		lines = append(lines, &Token{Prefix: keyPrefix, Text: "<" + keyKind + ">", Suffix: ":", NoExtraLine: true})
+	case "json":
+		lines = append(lines, &Token{Prefix: keyPrefix, Text: `"<` + keyKind + `>"`, Suffix: ": {", NoExtraLine: true})
+	default:
+		lines = append(lines, &Token{Prefix: keyPrefix, Text: "key", Kind: keyKind, NoExtraLine: true})
+		lines = append(lines, &Token{Prefix: keyPrefix, Text: "value", Suffix: " {", NoExtraLine: true})
+	}
+
+	toks, valNext := DumpMessage(val.Message(), f.WithDepth(f.depth-1).WithPrefix(keyPrefix+"  "))
+	lines = append(lines, toks...)
+	next = append(next, valNext...)
+
+	if !f.yaml {
+		lines[len(lines)-1].NoExtraLine = true
+		lines = append(lines, &Token{Prefix: keyPrefix, Text: "}", NoExtraLine: true}, &Token{Prefix: f.prefix, Text: "}"})
+	}
+	return lines, next
+}
+
+// dumpField dumps the field, expanding the message fields if depth allows.
+func dumpField(fld protoreflect.FieldDescriptor, f Formatter) ([]*Token, []protoreflect.FullName) {
+	switch {
+	case fld.IsMap():
+		return dumpMap(fld, f)
+
+	// In YAML and JSON, well-known types have their own representations.
+	// They are still documented, as in textproto, so that all the syntaxes
+	// document the same messages, and link to their documentation.
+	case fld.Kind() == protoreflect.MessageKind && wktKind(fld.Message(), f) != "":
+		tok := finalToken(fld, f, false)
+		tok.Kind = wktKind(fld.Message(), f)
+		tok.linkKind = string(fld.Message().FullName())
+		return []*Token{tok}, []protoreflect.FullName{fld.Message().FullName()}
+
+	case fld.Kind() == protoreflect.MessageKind && f.depth > 1:
+		return dumpExtendedMsg(fld, f)
+	}
+
+	var next []protoreflect.FullName
+	if fld.Kind() == protoreflect.MessageKind {
+		next = append(next, fld.Message().FullName())
+	}
+	return []*Token{finalToken(fld, f, false)}, next
+}
+
 func DumpMessage(md protoreflect.MessageDescriptor, f Formatter) ([]*Token, []protoreflect.FullName) {
+	toks, next, _ := dumpMessage(md, f)
+	return toks, next
+}
+
+// fieldStart is the index of a field's first token in the message's tokens.
+type fieldStart struct {
+	index int
+	fld   protoreflect.FieldDescriptor
+}
+
+// memberAnchor returns the HTML anchor of a field or an enum value, e.g.
+// "acme_config_Handler_file_dir". Extensions are named by their full names.
+func memberAnchor(parent, name string) string {
+	return strings.ReplaceAll(parent+"."+name, ".", "_")
+}
+
+// setFieldAnchors sets the anchors of the message's field lines.
+func setFieldAnchors(msg protoreflect.FullName, toks []*Token, starts []fieldStart) {
+	for _, fs := range starts {
+		name := string(fs.fld.Name())
+		if fs.fld.IsExtension() {
+			name = string(fs.fld.FullName())
+		}
+		toks[fs.index].Anchor = memberAnchor(string(msg), name)
+	}
+}
+
+// dumpMessage dumps the message, like DumpMessage, also returning where
+// each field's tokens start.
+func dumpMessage(md protoreflect.MessageDescriptor, f Formatter) ([]*Token, []protoreflect.FullName, []fieldStart) {
 	var nextMessageName []protoreflect.FullName
+	var starts []fieldStart
 
 	var lines []*Token
 
-	// We use this to catch duplication for oneof and enum fields.
-	done := map[string]bool{}
-
 	for i := 0; i < md.Fields().Len(); i++ {
 		fld := md.Fields().Get(i)
 
-		if fld.Kind() == protoreflect.MessageKind && f.depth > 1 {
-			toks, next := dumpExtendedMsg(fld, f)
-			lines = append(lines, toks...)
-			nextMessageName = append(nextMessageName, next...)
-		} else {
-			if tok := fieldToToken(md.Fields().Get(i), f, &done); tok != nil {
-				lines = append(lines, tok)
-			}
-			if fld.Kind() == protoreflect.MessageKind {
-				nextMessageName = append(nextMessageName, fld.Message().FullName())
+		toks, next := dumpField(fld, f)
+		nextMessageName = append(nextMessageName, next...)
+		starts = append(starts, fieldStart{index: len(lines), fld: fld})
+
+		// Oneof alternatives are documented as the other fields, the first one
+		// introducing the oneof.
+		if comment := oneofComment(fld, f); comment != "" {
+			if toks[0].Comment != "" {
+				comment += "\n" + toks[0].Comment
 			}
+			toks[0].Comment = comment
 		}
+		lines = append(lines, toks...)
 	}
 
-	return lines, nextMessageName
+	// Extensions follow the regular fields.
+	toks, next, extStarts := dumpExtensions(md, f)
+	for _, es := range extStarts {
+		starts = append(starts, fieldStart{index: len(lines) + es.index, fld: es.fld})
+	}
+	lines = append(lines, toks...)
+	nextMessageName = append(nextMessageName, next...)
+
+	return lines, nextMessageName, starts
 }
 
-func ArrangeIntoPackages(paths []string, l *logger.Logger) map[string][]string {
+// ArrangeIntoPackages arranges the given messages into groups (documentation
+// pages), as per the provided grouping.
+func ArrangeIntoPackages(paths []string, g Grouping, l *logger.Logger) map[string][]string {
 	packages := make(map[string][]string)
 	for _, path := range paths {
-		parts := strings.SplitN(path, ".", 3)
-		if len(parts) < 3 {
-			l.Warningf("Skipping %s, not enough parts in package", path)
-			continue
-		}
-		if parts[0] != "cloudprober" {
-			l.Warningf("Skipping %s, not a cloudprober package", path)
-			continue
-		}
-		packages[parts[1]] = append(packages[parts[1]], path)
+		pkg := g.Group(path)
+		l.Debugf("Placing %s in %s", path, pkg)
+		packages[pkg] = append(packages[pkg], path)
 	}
 	return packages
 }
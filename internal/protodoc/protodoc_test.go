@@ -17,8 +17,10 @@ package protodoc
 import (
 	"testing"
 
+	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
 func TestMain(m *testing.M) {
@@ -26,6 +28,50 @@ func TestMain(m *testing.M) {
 	m.Run()
 }
 
+// withTestProtos replaces the registry with the given protos, keyed by their
+// file names, for the duration of the test.
+func withTestProtos(t *testing.T, protos map[string]string) {
+	t.Helper()
+
+	var names []string
+	for name := range protos {
+		names = append(names, name)
+	}
+	p := protoparse.Parser{
+		Accessor:              protoparse.FileContentsFromMap(protos),
+		IncludeSourceCodeInfo: true,
+	}
+	fds, err := p.ParseFiles(names...)
+	if err != nil {
+		t.Fatalf("Error parsing test protos: %v", err)
+	}
+
+	files := &protoregistry.Files{}
+	for _, fd := range fds {
+		if err := files.RegisterFile(fd.UnwrapFile()); err != nil {
+			t.Fatalf("Error registering %s: %v", fd.GetName(), err)
+		}
+	}
+
+	oldFiles := Files
+	Files = files
+	t.Cleanup(func() { Files = oldFiles })
+}
+
+// tokenLines returns the tokens, processed for HTML, as the config lines
+// they are rendered to, e.g. "timeout: int32 | default: 10".
+func tokenLines(toks []*Token) []string {
+	var lines []string
+	for _, tok := range toks {
+		line := tok.Prefix + tok.Text
+		if tok.Kind != "" {
+			line += tok.Sep + tok.Kind
+		}
+		lines = append(lines, line+string(tok.Suffix))
+	}
+	return lines
+}
+
 func TestDumpMessage(t *testing.T) {
 	const fldName = "cloudprober.probes.ProbeDef.http_probe"
 
@@ -95,6 +141,37 @@ func TestDumpMessage(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "json,depth=2",
+			f:    Formatter{}.WithDepth(2).WithJSON(true),
+			wantToks: []*Token{
+				{
+					Kind:          "cloudprober.probes.http.Header",
+					Text:          `"header"`,
+					MessageHeader: true,
+					NoExtraLine:   true,
+					json:          true,
+					repeated:      true,
+				},
+				{
+					Kind:   "string",
+					Text:   `"name"`,
+					Prefix: "  ",
+					json:   true,
+				},
+				{
+					Kind:        "string",
+					Text:        `"value"`,
+					Prefix:      "  ",
+					NoExtraLine: true,
+					json:        true,
+				},
+				{
+					Kind: "",
+					Text: "}]",
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -112,10 +189,13 @@ func TestDumpMessage(t *testing.T) {
 func TestArrangeIntoPackages(t *testing.T) {
 	tests := []struct {
 		name  string
+		g     Grouping
 		paths []string
 		want  map[string][]string
 	}{
 		{
+			name: "default",
+			g:    DefaultGrouping,
 			paths: []string{
 				"cloudprober.probes.ProbeDef.interval_msec",
 				"cloudprober.probes.ProbeDef.timeout_msec",
@@ -129,10 +209,166 @@ func TestArrangeIntoPackages(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "prefix-outside-root",
+			g:    Grouping{By: GroupByPrefix, Root: "acme.config", Depth: 2},
+			paths: []string{
+				"acme.config.Config",
+				"acme.config.server.http.Handler",
+				"acme.config.server.Server",
+				"cloudprober.probes.ProbeDef",
+				"Top",
+			},
+			want: map[string][]string{
+				"config":             {"acme.config.Config"},
+				"server.http":        {"acme.config.server.http.Handler"},
+				"server":             {"acme.config.server.Server"},
+				"cloudprober.probes": {"cloudprober.probes.ProbeDef"},
+				"default":            {"Top"},
+			},
+		},
+		{
+			name: "package",
+			g:    Grouping{By: GroupByPackage},
+			paths: []string{
+				"cloudprober.probes.ProbeDef",
+				"cloudprober.probes.AdditionalLabel",
+				"cloudprober.probes.http.ProbeConf",
+			},
+			want: map[string][]string{
+				"cloudprober.probes":      {"cloudprober.probes.ProbeDef", "cloudprober.probes.AdditionalLabel"},
+				"cloudprober.probes.http": {"cloudprober.probes.http.ProbeConf"},
+			},
+		},
+		{
+			name: "file",
+			g:    Grouping{By: GroupByFile},
+			paths: []string{
+				"cloudprober.probes.ProbeDef",
+				"cloudprober.probes.http.ProbeConf",
+			},
+			want: map[string][]string{
+				"testdata_config":            {"cloudprober.probes.ProbeDef"},
+				"testdata_http_proto_config": {"cloudprober.probes.http.ProbeConf"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ArrangeIntoPackages(tt.paths, tt.g, nil))
+		})
+	}
+}
+
+func TestCheckGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		g       Grouping
+		names   []string
+		wantErr bool
+	}{
+		{
+			name:  "no-conflict",
+			g:     Grouping{Root: "acme"},
+			names: []string{"acme.Config", "acme.server.Server", "acme.server.http.Handler", "other.Msg"},
+		},
+		{
+			name:    "root-package-and-inner-package",
+			g:       Grouping{Root: "acme"},
+			names:   []string{"acme.Config", "acme.acme.Config"},
+			wantErr: true,
+		},
+		{
+			name:    "outside-root-and-inner-package",
+			g:       Grouping{Root: "acme"},
+			names:   []string{"acme.probes.Probe", "probes.Probe"},
+			wantErr: true,
+		},
+		{
+			name:    "overview",
+			g:       Grouping{Root: "acme"},
+			names:   []string{"acme.overview.Msg"},
+			wantErr: true,
+		},
+		{
+			name:    "package-without-package",
+			g:       Grouping{By: GroupByPackage},
+			names:   []string{"Top", "default.Msg"},
+			wantErr: true,
+		},
+		{
+			name:  "package",
+			g:     Grouping{By: GroupByPackage},
+			names: []string{"acme.Config", "acme.acme.Config"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			assert.Equal(t, tt.want, ArrangeIntoPackages(tt.paths, nil))
+			err := checkGroups(tt.names, tt.g)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
 		})
 	}
 }
+
+func TestGenerateDocsPageConflict(t *testing.T) {
+	withTestProtos(t, map[string]string{
+		"config.proto": `
+syntax = "proto3";
+package acme;
+import "inner.proto";
+
+message Config {
+  .acme.acme.Inner inner = 1;
+  Shared shared = 2;
+}
+
+message Shared {}
+`,
+		"inner.proto": `
+syntax = "proto3";
+package acme.acme;
+
+message Inner {}
+`,
+	})
+
+	roots := NewRoots([]protoreflect.FullName{"acme.Config"})
+	_, err := GenerateDocs(roots, nil, Formatter{}.WithGrouping(Grouping{Root: "acme"}), nil)
+	assert.ErrorContains(t, err, "page name conflict")
+
+	// Grouping by package tells them apart.
+	_, err = GenerateDocs(roots, nil, Formatter{}.WithGrouping(Grouping{By: GroupByPackage}), nil)
+	assert.NoError(t, err)
+}
+
+func TestDumpMessageJSON(t *testing.T) {
+	md, err := findMessage("acme.config.ServerConfig")
+	assert.NoError(t, err)
+
+	toks, next := DumpMessage(md, Formatter{}.WithJSON(true).WithDepth(2))
+	toks = ProcessTokensForHTML(toks, Formatter{}.WithJSON(true).withDocumented(map[string]bool{}))
+	assert.Equal(t, []string{
+		`"listenAddr": string | default: :8080`,
+		`"timeout": string, e.g. "1.5s"`,
+		`"maxRequestBytes": int64 | default: 1048576`,
+		`"logLevel": acme.config.ServerConfig.LogLevel | default: ERROR`,
+		`"handlers" map<string, acme.config.Handler>: {`,
+		`  "<string>": {`,
+		`    "fileDir": string`,
+		`    "backend": acme.config.Backend`,
+		`    "enabled": bool | default: true`,
+		`  }`,
+		`}`,
+		`"tlsConfig" acme.config.TLSConfig: {`,
+		`  "caCert": bytes`,
+		`}`,
+	}, tokenLines(toks))
+	assert.Equal(t, "// Server timeout.", toks[1].Comment)
+
+	// Well-known types are documented as in textproto.
+	assert.Contains(t, next, protoreflect.FullName("google.protobuf.Duration"))
+}
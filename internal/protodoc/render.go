@@ -0,0 +1,106 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+)
+
+// Supported outputs.
+const (
+	OutputHTML     = "html"
+	OutputMarkdown = "markdown"
+	OutputJSON     = "json"
+)
+
+// File is a generated output file.
+type File struct {
+	// Path of the file, relative to the output directory.
+	Path    string
+	Content []byte
+}
+
+// Renderer renders documentation pages in a specific output format.
+type Renderer interface {
+	// Render renders the pages into output files.
+	Render(pages []*Page) ([]*File, error)
+}
+
+// NewRenderer returns a renderer for the given output. Formatter should be
+// the one used to generate the pages.
+func NewRenderer(output string, f Formatter) (Renderer, error) {
+	switch output {
+	case OutputHTML, "":
+		return &htmlRenderer{}, nil
+	case OutputMarkdown:
+		return &markdownRenderer{format: f.Format()}, nil
+	case OutputJSON:
+		return &jsonRenderer{f: f}, nil
+	}
+	return nil, fmt.Errorf("unknown output: %s", output)
+}
+
+// pageTitle returns the title to show for the page.
+func pageTitle(page *Page) string {
+	if page.Title != "" {
+		return page.Title
+	}
+	if page.Name == OverviewPage {
+		return "Overview"
+	}
+	return page.Name
+}
+
+// renderPages renders each page into its own file.
+func renderPages(pages []*Page, pagePath func(*Page) string, render func(io.Writer, *Page) error) ([]*File, error) {
+	var files []*File
+	for _, page := range pages {
+		var buf bytes.Buffer
+		if err := render(&buf, page); err != nil {
+			return nil, fmt.Errorf("error rendering page %s: %v", page.Name, err)
+		}
+		files = append(files, &File{Path: pagePath(page), Content: buf.Bytes()})
+	}
+	return files, nil
+}
+
+// htmlRenderer renders pages as HTML fragments, one directory per page.
+type htmlRenderer struct {
+	// Templates to render the pages with, built-in templates if nil.
+	tmpl *template.Template
+}
+
+// NewHTMLRenderer returns a HTML renderer that renders the pages using the
+// given templates, e.g. the ones returned by LoadTemplates.
+func NewHTMLRenderer(tmpl *template.Template) Renderer {
+	return &htmlRenderer{tmpl: tmpl}
+}
+
+func htmlPagePath(page *Page) string {
+	return path.Join(page.Name, "index.html")
+}
+
+func (r *htmlRenderer) Render(pages []*Page) ([]*File, error) {
+	if r.tmpl == nil {
+		return renderPages(pages, htmlPagePath, WriteDoc)
+	}
+	return renderPages(pages, htmlPagePath, func(w io.Writer, page *Page) error {
+		return r.tmpl.ExecuteTemplate(w, PageTemplate, page)
+	})
+}
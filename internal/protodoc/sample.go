@@ -0,0 +1,482 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"gopkg.in/yaml.v3"
+)
+
+// rawJSON is a sample value written as is in JSON and YAML, e.g. "{}".
+type rawJSON string
+
+// sampleValue is a value in the sample config: a scalar or a message.
+type sampleValue struct {
+	// Scalar value: string, []byte, bool, int64, uint64, float64, enum value
+	// name (protoreflect.Name) or rawJSON.
+	scalar any
+
+	// Fields of the message values.
+	fields []*sampleField
+	msg    bool
+}
+
+// sampleField is a field in the sample config.
+type sampleField struct {
+	fd      protoreflect.FieldDescriptor
+	name    string
+	comment []string
+
+	// Values of the field: one value for the singular fields, list with one
+	// element for the repeated fields.
+	values   []*sampleValue
+	repeated bool
+
+	// Key for the map fields.
+	mapKey *sampleValue
+}
+
+// sampleGenerator generates the sample config tree for a message.
+type sampleGenerator struct {
+	f Formatter
+
+	// Messages on the current path, to stop at the recursive messages.
+	path map[protoreflect.FullName]bool
+}
+
+// wktSampleValue returns the JSON form of the well-known types, used for the
+// YAML and JSON samples. Any is not supported as it requires a type URL.
+func wktSampleValue(md protoreflect.MessageDescriptor) (v *sampleValue, ok bool, skip bool) {
+	switch md.FullName() {
+	case "google.protobuf.Any":
+		return nil, false, true
+	case "google.protobuf.Duration":
+		return &sampleValue{scalar: "0s"}, true, false
+	case "google.protobuf.Timestamp":
+		return &sampleValue{scalar: "1970-01-01T00:00:00Z"}, true, false
+	case "google.protobuf.FieldMask":
+		return &sampleValue{scalar: ""}, true, false
+	case "google.protobuf.Struct", "google.protobuf.Empty":
+		return &sampleValue{scalar: rawJSON("{}")}, true, false
+	case "google.protobuf.Value":
+		return &sampleValue{scalar: rawJSON("null")}, true, false
+	case "google.protobuf.ListValue":
+		return &sampleValue{scalar: rawJSON("[]")}, true, false
+	}
+	if md.ParentFile() != nil && md.ParentFile().Package() == "google.protobuf" && strings.HasSuffix(string(md.Name()), "Value") {
+		if valueFld := md.Fields().ByName("value"); valueFld != nil && md.Fields().Len() == 1 {
+			return &sampleValue{scalar: scalarSample(valueFld)}, true, false
+		}
+	}
+	return nil, false, false
+}
+
+// scalarSample returns the default value of the scalar field, or the zero
+// value of its type.
+func scalarSample(fld protoreflect.FieldDescriptor) any {
+	if fld.Kind() == protoreflect.EnumKind {
+		if ev := fld.DefaultEnumValue(); ev != nil {
+			return ev.Name()
+		}
+		return fld.Enum().Values().Get(0).Name()
+	}
+
+	v := fld.Default()
+	switch fld.Kind() {
+	case protoreflect.BoolKind:
+		return v.Bool()
+	case protoreflect.StringKind:
+		return v.String()
+	case protoreflect.BytesKind:
+		return v.Bytes()
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return v.Uint()
+	}
+	return v.Int()
+}
+
+// mapKeySample returns the key used for the map fields.
+func mapKeySample(fld protoreflect.FieldDescriptor) any {
+	if fld.Kind() == protoreflect.StringKind {
+		return "key"
+	}
+	return scalarSample(fld)
+}
+
+func commentLines(d protoreflect.Descriptor) []string {
+	comment := plainComment(d)
+	if comment == "" {
+		return nil
+	}
+	return strings.Split(comment, "\n")
+}
+
+// value returns the sample value of the field, not considering its
+// cardinality. It returns nil if field should be skipped.
+func (g *sampleGenerator) value(fld protoreflect.FieldDescriptor) *sampleValue {
+	if fld.Kind() != protoreflect.MessageKind {
+		return &sampleValue{scalar: scalarSample(fld)}
+	}
+
+	md := fld.Message()
+	if g.f.Format() != "textpb" {
+		if v, ok, skip := wktSampleValue(md); ok || skip {
+			return v
+		}
+	}
+	if g.path[md.FullName()] {
+		return nil
+	}
+	return g.message(md)
+}
+
+// fieldName returns the name of the field in the sample.
+func (g *sampleGenerator) fieldName(fld protoreflect.FieldDescriptor) string {
+	if g.f.Format() != "textpb" && g.f.useJSONNames() {
+		return fld.JSONName()
+	}
+	return string(fld.Name())
+}
+
+func (g *sampleGenerator) field(fld protoreflect.FieldDescriptor) *sampleField {
+	// Group fields use the message name in textpb, and are rare in configs.
+	if fld.Kind() == protoreflect.GroupKind {
+		return nil
+	}
+
+	sf := &sampleField{
+		fd:       fld,
+		name:     g.fieldName(fld),
+		comment:  commentLines(fld),
+		repeated: fld.Cardinality() == protoreflect.Repeated,
+	}
+
+	if fld.IsMap() {
+		v := g.value(fld.MapValue())
+		if v == nil {
+			return nil
+		}
+		sf.mapKey = &sampleValue{scalar: mapKeySample(fld.MapKey())}
+		sf.values = []*sampleValue{v}
+		return sf
+	}
+
+	v := g.value(fld)
+	if v == nil {
+		return nil
+	}
+	sf.values = []*sampleValue{v}
+	return sf
+}
+
+func (g *sampleGenerator) message(md protoreflect.MessageDescriptor) *sampleValue {
+	g.path[md.FullName()] = true
+	defer delete(g.path, md.FullName())
+
+	v := &sampleValue{msg: true}
+	doneOneofs := map[protoreflect.FullName]bool{}
+	for i := 0; i < md.Fields().Len(); i++ {
+		fld := md.Fields().Get(i)
+
+		oo := fld.ContainingOneof()
+		if oo == nil || oo.IsSynthetic() {
+			if sf := g.field(fld); sf != nil {
+				v.fields = append(v.fields, sf)
+			}
+			continue
+		}
+
+		// Show only the first alternative of the oneofs, that can be shown.
+		if doneOneofs[oo.FullName()] {
+			continue
+		}
+		for j := 0; j < oo.Fields().Len(); j++ {
+			sf := g.field(oo.Fields().Get(j))
+			if sf == nil {
+				continue
+			}
+			doneOneofs[oo.FullName()] = true
+
+			var names []string
+			for k := 0; k < oo.Fields().Len(); k++ {
+				names = append(names, g.fieldName(oo.Fields().Get(k)))
+			}
+			comment := append(commentLines(oo), commentLines(oo.Fields().Get(j))...)
+			sf.comment = append(comment, "Only one of: "+strings.Join(names, ", ")+".")
+			v.fields = append(v.fields, sf)
+			break
+		}
+	}
+	return v
+}
+
+func textScalar(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []byte:
+		return strconv.Quote(string(v))
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return "inf"
+		case math.IsInf(v, -1):
+			return "-inf"
+		case math.IsNaN(v):
+			return "nan"
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func jsonScalar(v any) string {
+	switch v := v.(type) {
+	case rawJSON:
+		return string(v)
+	case []byte:
+		v2, _ := json.Marshal(base64.StdEncoding.EncodeToString(v))
+		return string(v2)
+	case protoreflect.Name:
+		return `"` + string(v) + `"`
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return `"Infinity"`
+		case math.IsInf(v, -1):
+			return `"-Infinity"`
+		case math.IsNaN(v):
+			return `"NaN"`
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+func writeComment(b *strings.Builder, indent string, comment []string) {
+	for _, line := range comment {
+		b.WriteString(strings.TrimRight(indent+"# "+line, " ") + "\n")
+	}
+}
+
+func writeText(b *strings.Builder, fields []*sampleField, indent string) {
+	for _, sf := range fields {
+		writeComment(b, indent, sf.comment)
+		for _, v := range sf.values {
+			if sf.mapKey != nil {
+				b.WriteString(indent + sf.name + " {\n")
+				b.WriteString(indent + "  key: " + textScalar(sf.mapKey.scalar) + "\n")
+				writeTextValue(b, "value", v, indent+"  ")
+				b.WriteString(indent + "}\n")
+				continue
+			}
+			writeTextValue(b, sf.name, v, indent)
+		}
+	}
+}
+
+func writeTextValue(b *strings.Builder, name string, v *sampleValue, indent string) {
+	if !v.msg {
+		b.WriteString(indent + name + ": " + textScalar(v.scalar) + "\n")
+		return
+	}
+	if len(v.fields) == 0 {
+		b.WriteString(indent + name + " {}\n")
+		return
+	}
+	b.WriteString(indent + name + " {\n")
+	writeText(b, v.fields, indent+"  ")
+	b.WriteString(indent + "}\n")
+}
+
+// jsonMapKey returns the map key in the JSON form, where keys are always
+// strings. YAML samples use the same keys, as bare non-string keys (e.g.
+// false) don't convert to JSON.
+func jsonMapKey(key any) string {
+	if _, ok := key.(string); !ok {
+		key = fmt.Sprint(key)
+	}
+	return jsonScalar(key)
+}
+
+// yamlValue returns the YAML lines for the value, following a key (e.g.
+// "foo:") or a list item marker.
+func yamlValue(v *sampleValue, indent string) (inline string, lines []string) {
+	if !v.msg {
+		return " " + jsonScalar(v.scalar), nil
+	}
+	if len(v.fields) == 0 {
+		return " {}", nil
+	}
+	var b strings.Builder
+	writeYAML(&b, v.fields, indent)
+	return "", strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+}
+
+func writeYAML(b *strings.Builder, fields []*sampleField, indent string) {
+	for _, sf := range fields {
+		writeComment(b, indent, sf.comment)
+
+		v := sf.values[0]
+		switch {
+		case sf.mapKey != nil:
+			b.WriteString(indent + sf.name + ":\n")
+			inline, lines := yamlValue(v, indent+"    ")
+			b.WriteString(indent + "  " + jsonMapKey(sf.mapKey.scalar) + ":" + inline + "\n")
+			for _, line := range lines {
+				b.WriteString(line + "\n")
+			}
+
+		case sf.repeated:
+			b.WriteString(indent + sf.name + ":\n")
+			inline, lines := yamlValue(v, indent+"    ")
+			if inline != "" {
+				b.WriteString(indent + "  -" + inline + "\n")
+				continue
+			}
+			// Start the list item at the first non-comment line.
+			item := false
+			for _, line := range lines {
+				if !item && !strings.HasPrefix(strings.TrimSpace(line), "#") {
+					line = indent + "  - " + strings.TrimPrefix(line, indent+"    ")
+					item = true
+				}
+				b.WriteString(line + "\n")
+			}
+
+		default:
+			inline, lines := yamlValue(v, indent+"  ")
+			b.WriteString(indent + sf.name + ":" + inline + "\n")
+			for _, line := range lines {
+				b.WriteString(line + "\n")
+			}
+		}
+	}
+}
+
+func writeJSONValue(b *strings.Builder, v *sampleValue, indent string) {
+	if !v.msg {
+		b.WriteString(jsonScalar(v.scalar))
+		return
+	}
+	if len(v.fields) == 0 {
+		b.WriteString("{}")
+		return
+	}
+	b.WriteString("{\n")
+	for i, sf := range v.fields {
+		b.WriteString(indent + "  " + jsonScalar(sf.name) + ": ")
+		writeJSONFieldValue(b, sf, indent+"  ")
+		if i != len(v.fields)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(indent + "}")
+}
+
+// writeJSONFieldValue writes the field's value: an object for the map
+// fields, an array for the repeated fields.
+func writeJSONFieldValue(b *strings.Builder, sf *sampleField, indent string) {
+	switch {
+	case sf.mapKey != nil:
+		b.WriteString("{\n" + indent + "  " + jsonMapKey(sf.mapKey.scalar) + ": ")
+		writeJSONValue(b, sf.values[0], indent+"  ")
+		b.WriteString("\n" + indent + "}")
+	case sf.repeated:
+		b.WriteString("[\n" + indent + "  ")
+		writeJSONValue(b, sf.values[0], indent+"  ")
+		b.WriteString("\n" + indent + "]")
+	default:
+		writeJSONValue(b, sf.values[0], indent)
+	}
+}
+
+// parseSample parses the config in the formatter's syntax into a message of
+// the given type. YAML is parsed by converting it to JSON first.
+func parseSample(md protoreflect.MessageDescriptor, b []byte, f Formatter) error {
+	msg := dynamicpb.NewMessage(md)
+	resolver := dynamicpb.NewTypes(Files)
+
+	switch f.Format() {
+	case "textpb":
+		return prototext.UnmarshalOptions{Resolver: resolver}.Unmarshal(b, msg)
+	case "yaml":
+		var v any
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		if v == nil {
+			v = map[string]any{}
+		}
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+	return protojson.UnmarshalOptions{Resolver: resolver}.Unmarshal(b, msg)
+}
+
+// GenerateSample generates a sample config for the given root message, in the
+// formatter's syntax. Every field is populated by its default value, or
+// the zero value of its type, and the first alternative of each oneof is
+// shown. Repeated and map fields get one element. Field comments are added
+// as config comments, except for JSON. Recursive messages are populated only
+// once along a path.
+//
+// Generated config is verified to parse as the message.
+func GenerateSample(rootMsg protoreflect.FullName, f Formatter) ([]byte, error) {
+	md, err := findMessage(rootMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &sampleGenerator{f: f, path: map[protoreflect.FullName]bool{}}
+	v := g.message(md)
+
+	var b strings.Builder
+	switch f.Format() {
+	case "textpb":
+		writeComment(&b, "", commentLines(md))
+		writeText(&b, v.fields, "")
+	case "yaml":
+		writeComment(&b, "", commentLines(md))
+		writeYAML(&b, v.fields, "")
+	case "json":
+		writeJSONValue(&b, v, "")
+		b.WriteString("\n")
+	}
+
+	out := []byte(b.String())
+	if err := parseSample(md, out, f); err != nil {
+		return nil, fmt.Errorf("generated sample for %s doesn't parse: %v", rootMsg, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateSample(t *testing.T) {
+	tests := []struct {
+		name string
+		f    Formatter
+		want string
+	}{
+		{
+			name: "textpb",
+			f:    Formatter{},
+			want: `# Server configuration.
+# Address to listen on.
+listen_addr: ":8080"
+# Server timeout.
+timeout {
+  seconds: 0
+  nanos: 0
+}
+# Maximum request size in bytes.
+max_request_bytes: 1048576
+log_level: ERROR
+# Handlers, keyed by path.
+handlers {
+  key: "key"
+  value {
+    # Serve files from a directory.
+    # Only one of: file_dir, backend.
+    file_dir: ""
+    enabled: true
+  }
+}
+# TLS is disabled if not set.
+tls_config {
+  ca_cert: ""
+}
+`,
+		},
+		{
+			name: "yaml",
+			f:    Formatter{}.WithYAML(true, false),
+			want: `# Server configuration.
+# Address to listen on.
+listen_addr: ":8080"
+# Server timeout.
+timeout: "0s"
+# Maximum request size in bytes.
+max_request_bytes: 1048576
+log_level: "ERROR"
+# Handlers, keyed by path.
+handlers:
+  "key":
+    # Serve files from a directory.
+    # Only one of: file_dir, backend.
+    file_dir: ""
+    enabled: true
+# TLS is disabled if not set.
+tls_config:
+  ca_cert: ""
+`,
+		},
+		{
+			name: "yaml,json_names",
+			f:    Formatter{}.WithYAML(true, true),
+			want: `# Server configuration.
+# Address to listen on.
+listenAddr: ":8080"
+# Server timeout.
+timeout: "0s"
+# Maximum request size in bytes.
+maxRequestBytes: 1048576
+logLevel: "ERROR"
+# Handlers, keyed by path.
+handlers:
+  "key":
+    # Serve files from a directory.
+    # Only one of: fileDir, backend.
+    fileDir: ""
+    enabled: true
+# TLS is disabled if not set.
+tlsConfig:
+  caCert: ""
+`,
+		},
+		{
+			name: "json",
+			f:    Formatter{}.WithJSON(true),
+			want: `{
+  "listenAddr": ":8080",
+  "timeout": "0s",
+  "maxRequestBytes": 1048576,
+  "logLevel": "ERROR",
+  "handlers": {
+    "key": {
+      "fileDir": "",
+      "enabled": true
+    }
+  },
+  "tlsConfig": {
+    "caCert": ""
+  }
+}
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := GenerateSample("acme.config.ServerConfig", test.f)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, string(b))
+
+			// Repeated fields, and comments within the list items.
+			_, err = GenerateSample("cloudprober.probes.ProbeDef", test.f)
+			assert.NoError(t, err)
+		})
+	}
+
+	_, err := GenerateSample("acme.config.Unknown", Formatter{})
+	assert.Error(t, err)
+}
+
+func TestGenerateSampleMapKeysAndRecursion(t *testing.T) {
+	withTestProtos(t, map[string]string{"test.proto": `
+syntax = "proto2";
+package test;
+
+message Config {
+  map<int32, string> by_id = 1;
+  map<bool, string> by_flag = 2;
+  required Node root = 3;
+}
+
+message Node {
+  required string name = 1;
+  repeated Node children = 2;
+}
+`})
+
+	tests := []struct {
+		name string
+		f    Formatter
+		want string
+	}{
+		{
+			name: "textpb",
+			f:    Formatter{},
+			want: `by_id {
+  key: 0
+  value: ""
+}
+by_flag {
+  key: false
+  value: ""
+}
+root {
+  name: ""
+}
+`,
+		},
+		{
+			name: "yaml",
+			f:    Formatter{}.WithYAML(true, false),
+			want: `by_id:
+  "0": ""
+by_flag:
+  "false": ""
+root:
+  name: ""
+`,
+		},
+		{
+			name: "json",
+			f:    Formatter{}.WithJSON(true),
+			want: `{
+  "byId": {
+    "0": ""
+  },
+  "byFlag": {
+    "false": ""
+  },
+  "root": {
+    "name": ""
+  }
+}
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := GenerateSample("test.Config", test.f)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, string(b))
+		})
+	}
+}
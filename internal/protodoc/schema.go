@@ -0,0 +1,302 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+type schema map[string]any
+
+// wktSchemas are the schemas for the well-known types, as per their JSON
+// representation.
+var wktSchemas = map[protoreflect.FullName]schema{
+	"google.protobuf.Duration":  {"type": "string", "pattern": `^-?[0-9]+(\.[0-9]+)?s$`},
+	"google.protobuf.Timestamp": {"type": "string", "format": "date-time"},
+	"google.protobuf.FieldMask": {"type": "string"},
+	"google.protobuf.Struct":    {"type": "object"},
+	"google.protobuf.Value":     {},
+	"google.protobuf.ListValue": {"type": "array"},
+	"google.protobuf.Any":       {"type": "object", "properties": schema{"@type": schema{"type": "string"}}, "required": []string{"@type"}},
+	"google.protobuf.Empty":     {"type": "object", "additionalProperties": false},
+
+	"google.protobuf.DoubleValue": {"type": []string{"number", "null"}},
+	"google.protobuf.FloatValue":  {"type": []string{"number", "null"}},
+	"google.protobuf.Int64Value":  {"type": []string{"integer", "string", "null"}},
+	"google.protobuf.UInt64Value": {"type": []string{"integer", "string", "null"}},
+	"google.protobuf.Int32Value":  {"type": []string{"integer", "null"}},
+	"google.protobuf.UInt32Value": {"type": []string{"integer", "null"}},
+	"google.protobuf.BoolValue":   {"type": []string{"boolean", "null"}},
+	"google.protobuf.StringValue": {"type": []string{"string", "null"}},
+	"google.protobuf.BytesValue":  {"type": []string{"string", "null"}, "contentEncoding": "base64"},
+}
+
+// schemaGenerator generates JSON schema for messages, adding the definition
+// of each message to defs.
+type schemaGenerator struct {
+	f    Formatter
+	defs map[string]schema
+}
+
+// fieldNames returns the names the field can be spelled with: protojson
+// accepts both the proto and the JSON names. Names preferred by the formatter
+// come first.
+func (g *schemaGenerator) fieldNames(fld protoreflect.FieldDescriptor) []string {
+	// Extensions are referred to by their full names.
+	if fld.IsExtension() {
+		return []string{"[" + string(fld.FullName()) + "]"}
+	}
+	names := []string{string(fld.Name()), fld.JSONName()}
+	if names[0] == names[1] {
+		return names[:1]
+	}
+	if g.f.useJSONNames() {
+		names[0], names[1] = names[1], names[0]
+	}
+	return names
+}
+
+func (g *schemaGenerator) msgRef(md protoreflect.MessageDescriptor) schema {
+	if ws, ok := wktSchemas[md.FullName()]; ok {
+		// Return a copy, as field schemas are modified by the caller.
+		s := schema{}
+		for k, v := range ws {
+			s[k] = v
+		}
+		return s
+	}
+	name := string(md.FullName())
+	if _, ok := g.defs[name]; !ok {
+		// Add a placeholder first to handle recursive messages.
+		g.defs[name] = schema{}
+		g.defs[name] = g.msgSchema(md)
+	}
+	return schema{"$ref": "#/$defs/" + name}
+}
+
+// enumSchema returns the schema for the enum: value names, or their numbers,
+// which protojson accepts as well.
+func enumSchema(ed protoreflect.EnumDescriptor) schema {
+	var vals []any
+	for i := 0; i < ed.Values().Len(); i++ {
+		vals = append(vals, string(ed.Values().Get(i).Name()))
+	}
+	for i := 0; i < ed.Values().Len(); i++ {
+		vals = append(vals, int32(ed.Values().Get(i).Number()))
+	}
+	return schema{"enum": vals}
+}
+
+func (g *schemaGenerator) singularSchema(fld protoreflect.FieldDescriptor) schema {
+	switch fld.Kind() {
+	case protoreflect.BoolKind:
+		return schema{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return schema{"type": "integer"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// 64-bit integers are represented as strings in JSON, but numbers are
+		// accepted too.
+		return schema{"type": []string{"integer", "string"}}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		// Non-finite values are represented as strings in JSON.
+		return schema{"anyOf": []schema{{"type": "number"}, {"enum": nonFiniteFloats}}}
+	case protoreflect.StringKind:
+		return schema{"type": "string"}
+	case protoreflect.BytesKind:
+		return schema{"type": "string", "contentEncoding": "base64"}
+	case protoreflect.EnumKind:
+		return enumSchema(fld.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return g.msgRef(fld.Message())
+	}
+	return schema{}
+}
+
+// nonFiniteFloats are the JSON representations of the non-finite floats, as
+// used by protojson.
+var nonFiniteFloats = []string{"Infinity", "-Infinity", "NaN"}
+
+// defaultValue returns the default value of the field, in its protojson
+// representation.
+func defaultValue(fld protoreflect.FieldDescriptor) any {
+	v := fld.Default()
+	switch fld.Kind() {
+	case protoreflect.EnumKind:
+		return string(fld.DefaultEnumValue().Name())
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		switch f := v.Float(); {
+		case math.IsInf(f, 1):
+			return nonFiniteFloats[0]
+		case math.IsInf(f, -1):
+			return nonFiniteFloats[1]
+		case math.IsNaN(f):
+			return nonFiniteFloats[2]
+		}
+	}
+	return v.Interface()
+}
+
+func (g *schemaGenerator) fieldSchema(fld protoreflect.FieldDescriptor) schema {
+	var s schema
+	switch {
+	case fld.IsMap():
+		s = schema{"type": "object", "additionalProperties": g.singularSchema(fld.MapValue())}
+	case fld.IsList():
+		s = schema{"type": "array", "items": g.singularSchema(fld)}
+	default:
+		s = g.singularSchema(fld)
+		if fld.HasDefault() {
+			s["default"] = defaultValue(fld)
+		}
+	}
+
+	// Since draft 2019-09, keywords next to $ref are not ignored.
+	if desc := plainComment(fld); desc != "" {
+		s["description"] = desc
+	}
+	return s
+}
+
+// requiredField returns the schema requiring the field, with any of its
+// names.
+func requiredField(names []string) schema {
+	if len(names) == 1 {
+		return schema{"required": names}
+	}
+	var alts []schema
+	for _, name := range names {
+		alts = append(alts, schema{"required": []string{name}})
+	}
+	return schema{"anyOf": alts}
+}
+
+func requiredOneof(flds protoreflect.FieldDescriptors, g *schemaGenerator) []schema {
+	var alts []schema
+	for i := 0; i < flds.Len(); i++ {
+		alts = append(alts, requiredField(g.fieldNames(flds.Get(i))))
+	}
+	return alts
+}
+
+func (g *schemaGenerator) msgSchema(md protoreflect.MessageDescriptor) schema {
+	s := schema{
+		"type":                 "object",
+		"additionalProperties": false,
+	}
+	if desc := plainComment(md); desc != "" {
+		s["description"] = desc
+	}
+
+	// Constraints that don't fit in "required", e.g. required fields with
+	// two spellings and oneofs.
+	var constraints []schema
+
+	props := schema{}
+	var required []string
+	for i := 0; i < md.Fields().Len(); i++ {
+		fld := md.Fields().Get(i)
+		names := g.fieldNames(fld)
+		for _, name := range names {
+			props[name] = g.fieldSchema(fld)
+		}
+		if fld.Cardinality() != protoreflect.Required {
+			continue
+		}
+		if len(names) == 1 {
+			required = append(required, names[0])
+		} else {
+			constraints = append(constraints, requiredField(names))
+		}
+	}
+	for _, xd := range findExtensions(md) {
+		for _, name := range g.fieldNames(xd) {
+			props[name] = g.fieldSchema(xd)
+		}
+	}
+	s["properties"] = props
+	if len(required) > 0 {
+		s["required"] = required
+	}
+
+	// Oneof fields: at most one of the alternatives can be set.
+	var oneofs int
+	for i := 0; i < md.Oneofs().Len(); i++ {
+		ood := md.Oneofs().Get(i)
+		if ood.IsSynthetic() || ood.Fields().Len() < 2 {
+			continue
+		}
+		alts := requiredOneof(ood.Fields(), g)
+		constraints = append(constraints, schema{
+			"oneOf": append(alts, schema{"not": schema{"anyOf": requiredOneof(ood.Fields(), g)}}),
+		})
+		oneofs++
+	}
+	switch {
+	case len(constraints) == 0:
+	case len(constraints) == 1 && oneofs == 1:
+		s["oneOf"] = constraints[0]["oneOf"]
+	default:
+		s["allOf"] = constraints
+	}
+
+	return s
+}
+
+// GenerateJSONSchema generates a JSON schema (draft 2020-12) for the given
+// root message, describing its YAML/JSON representation. Fields can be
+// spelled with their proto or JSON names, as protojson accepts both.
+func GenerateJSONSchema(rootMsg protoreflect.FullName, f Formatter) ([]byte, error) {
+	md, err := findMessage(rootMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &schemaGenerator{f: f, defs: map[string]schema{}}
+	root := g.msgRef(md)
+
+	s := schema{
+		"$schema": jsonSchemaDraft,
+		"title":   string(rootMsg),
+		"$defs":   g.defs,
+	}
+	for k, v := range root {
+		s[k] = v
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// JSONSchemaPath returns the path of the JSON schema file for the root
+// message, relative to the output directory.
+func JSONSchemaPath(root *Root) string {
+	return root.Page + ".schema.json"
+}
@@ -0,0 +1,193 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateJSONSchema(t *testing.T) {
+	b, err := GenerateJSONSchema("acme.config.ServerConfig", Formatter{}.WithYAML(true, true))
+	assert.NoError(t, err)
+
+	var s map[string]any
+	assert.NoError(t, json.Unmarshal(b, &s))
+
+	assert.Equal(t, jsonSchemaDraft, s["$schema"])
+	assert.Equal(t, "#/$defs/acme.config.ServerConfig", s["$ref"])
+
+	defs := s["$defs"].(map[string]any)
+	assert.Len(t, defs, 4, "defs: Backend, Handler, ServerConfig and TLSConfig")
+
+	server := defs["acme.config.ServerConfig"].(map[string]any)
+	assert.Equal(t, "Server configuration.", server["description"])
+	props := server["properties"].(map[string]any)
+
+	wantProps := map[string]any{
+		"listenAddr": map[string]any{"type": "string", "default": ":8080", "description": "Address to listen on."},
+		"timeout": map[string]any{
+			"type":        "string",
+			"pattern":     `^-?[0-9]+(\.[0-9]+)?s$`,
+			"description": "Server timeout.",
+		},
+		"maxRequestBytes": map[string]any{"type": []any{"integer", "string"}, "default": float64(1048576), "description": "Maximum request size in bytes."},
+		"logLevel":        map[string]any{"enum": []any{"ERROR", "DEBUG", "VERBOSE", float64(1), float64(2), float64(3)}, "default": "ERROR"},
+		"handlers": map[string]any{
+			"type":                 "object",
+			"additionalProperties": map[string]any{"$ref": "#/$defs/acme.config.Handler"},
+			"description":          "Handlers, keyed by path.",
+		},
+		"tlsConfig": map[string]any{"$ref": "#/$defs/acme.config.TLSConfig", "description": "TLS is disabled if not set."},
+	}
+	// Proto names are accepted as well.
+	for jsonName, protoName := range map[string]string{
+		"listenAddr":      "listen_addr",
+		"maxRequestBytes": "max_request_bytes",
+		"logLevel":        "log_level",
+		"tlsConfig":       "tls_config",
+	} {
+		wantProps[protoName] = wantProps[jsonName]
+	}
+	assert.Equal(t, wantProps, props)
+
+	handler := defs["acme.config.Handler"].(map[string]any)
+	assert.Len(t, handler["oneOf"], 3)
+	assert.Equal(t, map[string]any{"anyOf": []any{
+		map[string]any{"required": []any{"fileDir"}},
+		map[string]any{"required": []any{"file_dir"}},
+	}}, handler["oneOf"].([]any)[0])
+
+	backend := defs["acme.config.Backend"].(map[string]any)
+	assert.Equal(t, []any{"address"}, backend["required"])
+	assert.Equal(t, "#/$defs/acme.config.Backend", backend["properties"].(map[string]any)["fallback"].(map[string]any)["items"].(map[string]any)["$ref"])
+
+	tls := defs["acme.config.TLSConfig"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "string", "contentEncoding": "base64"}, tls["properties"].(map[string]any)["caCert"])
+
+	_, err = GenerateJSONSchema("acme.config.Unknown", Formatter{})
+	assert.Error(t, err)
+}
+
+func TestGenerateJSONSchemaExtensions(t *testing.T) {
+	b, err := GenerateJSONSchema("cloudprober.probes.ProbeDef", Formatter{}.WithYAML(true, false))
+	assert.NoError(t, err)
+
+	var s map[string]any
+	assert.NoError(t, json.Unmarshal(b, &s))
+
+	defs := s["$defs"].(map[string]any)
+	props := defs["cloudprober.probes.ProbeDef"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"$ref":        "#/$defs/cloudprober.probes.udp.ProbeConf",
+		"description": "UDP probe, added as an extension.",
+	}, props["[cloudprober.probes.udp.udp_probe]"])
+	assert.Contains(t, defs, "cloudprober.probes.udp.ProbeConf")
+}
+
+func TestGenerateJSONSchemaFieldNames(t *testing.T) {
+	withTestProtos(t, map[string]string{
+		"test.proto": `
+syntax = "proto2";
+package test;
+
+message Config {
+  required string host_name = 1;
+  oneof source {
+    string file_path = 2;
+    string url = 3;
+  }
+}
+`,
+	})
+
+	b, err := GenerateJSONSchema("test.Config", Formatter{}.WithYAML(true, false))
+	assert.NoError(t, err)
+
+	var s map[string]any
+	assert.NoError(t, json.Unmarshal(b, &s))
+
+	config := s["$defs"].(map[string]any)["test.Config"].(map[string]any)
+	var propNames []string
+	for name := range config["properties"].(map[string]any) {
+		propNames = append(propNames, name)
+	}
+	assert.ElementsMatch(t, []string{"host_name", "hostName", "file_path", "filePath", "url"}, propNames)
+	assert.Equal(t, false, config["additionalProperties"])
+	assert.NotContains(t, config, "required")
+	assert.NotContains(t, config, "oneOf")
+
+	requireEither := func(names ...string) map[string]any {
+		var alts []any
+		for _, name := range names {
+			alts = append(alts, map[string]any{"required": []any{name}})
+		}
+		return map[string]any{"anyOf": alts}
+	}
+	assert.Equal(t, []any{
+		requireEither("host_name", "hostName"),
+		map[string]any{"oneOf": []any{
+			requireEither("file_path", "filePath"),
+			map[string]any{"required": []any{"url"}},
+			map[string]any{"not": map[string]any{"anyOf": []any{
+				requireEither("file_path", "filePath"),
+				map[string]any{"required": []any{"url"}},
+			}}},
+		}},
+	}, config["allOf"])
+}
+
+func TestGenerateJSONSchemaDefaults(t *testing.T) {
+	withTestProtos(t, map[string]string{
+		"test.proto": `
+syntax = "proto2";
+package test;
+
+message Config {
+  optional bytes magic = 1 [default = "\x01\x02abc"];
+  optional double max = 2 [default = inf];
+  optional float min = 3 [default = -inf];
+  optional double missing = 4 [default = nan];
+  optional double ratio = 5 [default = 0.5];
+}
+`,
+	})
+
+	b, err := GenerateJSONSchema("test.Config", Formatter{}.WithYAML(true, false))
+	assert.NoError(t, err)
+
+	var s map[string]any
+	assert.NoError(t, json.Unmarshal(b, &s))
+
+	props := s["$defs"].(map[string]any)["test.Config"].(map[string]any)["properties"].(map[string]any)
+	defaults := map[string]any{}
+	for name, prop := range props {
+		defaults[name] = prop.(map[string]any)["default"]
+	}
+	assert.Equal(t, map[string]any{
+		"magic":   "AQJhYmM=",
+		"max":     "Infinity",
+		"min":     "-Infinity",
+		"missing": "NaN",
+		"ratio":   0.5,
+	}, defaults)
+
+	assert.Equal(t, []any{
+		map[string]any{"type": "number"},
+		map[string]any{"enum": []any{"Infinity", "-Infinity", "NaN"}},
+	}, props["ratio"].(map[string]any)["anyOf"])
+}
@@ -0,0 +1,243 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SearchIndexFile is the name of the file the search index is written to.
+const SearchIndexFile = "search-index.json"
+
+// Kinds of the search index entries.
+const (
+	SearchKindMessage   = "message"
+	SearchKindField     = "field"
+	SearchKindEnum      = "enum"
+	SearchKindEnumValue = "enum_value"
+	SearchKindService   = "service"
+	SearchKindMethod    = "method"
+)
+
+// SearchIndex is the search index of the documentation, used by the search
+// box in the HTML pages.
+type SearchIndex struct {
+	Entries []*SearchEntry `json:"entries"`
+}
+
+// SearchEntry is a message, field, enum, enum value, service or method in
+// the search index.
+type SearchEntry struct {
+	Kind string `json:"kind"`
+
+	// Name of the entry: full name for the messages, enums and services, name
+	// for the fields, enum values and methods.
+	Name string `json:"name"`
+
+	// Full name of the message containing the field, of the enum containing
+	// the enum value, or of the service containing the method.
+	Parent string `json:"parent,omitempty"`
+
+	Comment string `json:"comment,omitempty"`
+
+	// URL of the documentation, relative to the pages, like the links in
+	// them. Fields, enum values and methods link to their own lines.
+	URL string `json:"url"`
+}
+
+// BuildSearchIndex builds the search index for the given pages. Formatter
+// should be the one used to generate the pages.
+func BuildSearchIndex(pages []*Page, f Formatter) (*SearchIndex, error) {
+	m, err := BuildModel(pages, f)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &SearchIndex{}
+	for _, mm := range m.Messages {
+		idx.Entries = append(idx.Entries, &SearchEntry{
+			Kind:    SearchKindMessage,
+			Name:    mm.Name,
+			Comment: mm.Comment,
+			URL:     mm.URL,
+		})
+		for _, fld := range append(mm.Fields, mm.Extensions...) {
+			idx.Entries = append(idx.Entries, &SearchEntry{
+				Kind:    SearchKindField,
+				Name:    fld.Name,
+				Parent:  mm.Name,
+				Comment: fld.Comment,
+				URL:     anchorURL(mm.URL, memberAnchor(mm.Name, fld.Name)),
+			})
+		}
+	}
+
+	for _, me := range m.Enums {
+		idx.Entries = append(idx.Entries, &SearchEntry{
+			Kind:    SearchKindEnum,
+			Name:    me.Name,
+			Comment: me.Comment,
+			URL:     me.URL,
+		})
+		for _, ev := range me.Values {
+			idx.Entries = append(idx.Entries, &SearchEntry{
+				Kind:    SearchKindEnumValue,
+				Name:    ev.Name,
+				Parent:  me.Name,
+				Comment: ev.Comment,
+				URL:     anchorURL(me.URL, memberAnchor(me.Name, ev.Name)),
+			})
+		}
+	}
+
+	for _, ms := range m.Services {
+		idx.Entries = append(idx.Entries, &SearchEntry{
+			Kind:    SearchKindService,
+			Name:    ms.Name,
+			Comment: ms.Comment,
+			URL:     ms.URL,
+		})
+		for _, mm := range ms.Methods {
+			idx.Entries = append(idx.Entries, &SearchEntry{
+				Kind:    SearchKindMethod,
+				Name:    mm.Name,
+				Parent:  ms.Name,
+				Comment: mm.Comment,
+				URL:     mm.URL,
+			})
+		}
+	}
+
+	sort.SliceStable(idx.Entries, func(i, j int) bool { return idx.Entries[i].Name < idx.Entries[j].Name })
+	return idx, nil
+}
+
+// anchorURL returns the URL of the anchor on the page of the given URL,
+// replacing the URL's anchor, if any.
+func anchorURL(url, anchor string) string {
+	page, _, _ := strings.Cut(url, "#")
+	return page + "#" + anchor
+}
+
+// searchIndexURL returns the URL of the search index, relative to the pages
+// the same way as the links between them.
+func searchIndexURL(f Formatter) string {
+	return f.docURL(SearchIndexFile)
+}
+
+// searchIndexRenderer wraps a renderer, adding the search index to its
+// output.
+type searchIndexRenderer struct {
+	r Renderer
+	f Formatter
+}
+
+// NewSearchIndexRenderer returns a renderer that renders the pages using r,
+// and adds the search index for them. Formatter should be the one used to
+// generate the pages.
+func NewSearchIndexRenderer(r Renderer, f Formatter) Renderer {
+	return &searchIndexRenderer{r: r, f: f}
+}
+
+func (r *searchIndexRenderer) Render(pages []*Page) ([]*File, error) {
+	files, err := r.r.Render(pages)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := BuildSearchIndex(pages, r.f)
+	if err != nil {
+		return nil, fmt.Errorf("error building search index: %v", err)
+	}
+	b, err := json.Marshal(idx)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling search index: %v", err)
+	}
+	return append(files, &File{Path: SearchIndexFile, Content: b}), nil
+}
+
+// SearchTmpl is the "search" partial used when the search index is
+// generated: a search box looking up the search index. It's rendered at the
+// top of the pages, or in the sidebar in the site mode.
+var SearchTmpl = `
+<div class="protodoc-search">
+<input type="search" placeholder="Search messages and fields" aria-label="Search">
+<ul class="protodoc-search-results"></ul>
+</div>
+<style>
+.protodoc-search input {
+    width: 100%;
+    box-sizing: border-box;
+    padding: 4px;
+}
+.protodoc-search-results {
+    list-style: none;
+    padding-left: 0;
+}
+.protodoc-search-results .parent {
+    color: #888;
+    font-size: smaller;
+}
+</style>
+<script>
+(function() {
+  var box = document.currentScript.previousElementSibling.previousElementSibling;
+  var input = box.querySelector("input");
+  var results = box.querySelector("ul");
+  var entries = null;
+
+  function show(query) {
+    results.innerHTML = "";
+    query = query.trim().toLowerCase();
+    if (!query || !entries) {
+      return;
+    }
+    var matches = entries.filter(function(e) {
+      return e.name.toLowerCase().indexOf(query) !== -1;
+    });
+    matches.sort(function(a, b) {
+      return (a.name.toLowerCase() !== query) - (b.name.toLowerCase() !== query);
+    });
+    matches.slice(0, 50).forEach(function(e) {
+      var li = document.createElement("li");
+      var a = document.createElement("a");
+      a.href = e.url;
+      a.textContent = e.name;
+      a.title = e.comment || "";
+      li.appendChild(a);
+      if (e.parent) {
+        var span = document.createElement("span");
+        span.className = "parent";
+        span.textContent = " " + e.parent;
+        li.appendChild(span);
+      }
+      results.appendChild(li);
+    });
+  }
+
+  input.addEventListener("input", function() {
+    if (entries) {
+      show(input.value);
+      return;
+    }
+    fetch({{ searchIndexURL }})
+      .then(function(resp) { return resp.json(); })
+      .then(function(idx) { entries = idx.entries; show(input.value); });
+  });
+})();
+</script>`
@@ -0,0 +1,99 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestSearchIndex(t *testing.T) {
+	c := &Config{SearchIndex: true, Grouping: GroupingConfig{Root: "acme"}}
+	assert.NoError(t, c.Validate())
+
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"acme.config.ServerConfig"}), nil, c.Formatter(), nil)
+	assert.NoError(t, err)
+
+	files, err := c.Renderer().Render(pages)
+	assert.NoError(t, err)
+
+	last := files[len(files)-1]
+	assert.Equal(t, SearchIndexFile, last.Path)
+	idx := &SearchIndex{}
+	assert.NoError(t, json.Unmarshal(last.Content, idx))
+
+	find := func(kind, name, parent string) *SearchEntry {
+		for _, e := range idx.Entries {
+			if e.Kind == kind && e.Name == name && e.Parent == parent {
+				return e
+			}
+		}
+		return nil
+	}
+	assert.Equal(t, &SearchEntry{Kind: SearchKindMessage, Name: "acme.config.Handler", Comment: "Handler for a path.", URL: "../config#acme_config_Handler"}, find(SearchKindMessage, "acme.config.Handler", ""))
+	assert.Equal(t, &SearchEntry{Kind: SearchKindField, Name: "file_dir", Parent: "acme.config.Handler", Comment: "Serve files from a directory.", URL: "../config#acme_config_Handler_file_dir"}, find(SearchKindField, "file_dir", "acme.config.Handler"))
+	assert.Equal(t, &SearchEntry{Kind: SearchKindField, Name: "listen_addr", Parent: "acme.config.ServerConfig", Comment: "Address to listen on.", URL: "../overview#acme_config_ServerConfig_listen_addr"}, find(SearchKindField, "listen_addr", "acme.config.ServerConfig"))
+	assert.Equal(t, &SearchEntry{Kind: SearchKindEnumValue, Name: "DEBUG", Parent: "acme.config.ServerConfig.LogLevel", Comment: "Log everything.", URL: "../config#acme_config_ServerConfig_LogLevel_DEBUG"}, find(SearchKindEnumValue, "DEBUG", "acme.config.ServerConfig.LogLevel"))
+
+	// Field and enum value entries point at their lines.
+	assert.Contains(t, string(files[0].Content), `<span id="acme_config_ServerConfig_listen_addr"></span>`)
+	assert.Contains(t, string(files[1].Content), `<span id="acme_config_Handler_file_dir"></span>`)
+	assert.Contains(t, string(files[1].Content), `<span id="acme_config_ServerConfig_LogLevel_DEBUG"></span>`)
+
+	// Search box is added to the pages.
+	assert.Contains(t, string(files[0].Content), `<div class="protodoc-search">`)
+	assert.Contains(t, string(files[0].Content), `fetch("../search-index.json")`)
+
+	assert.Error(t, (&Config{SearchIndex: true, Output: OutputMarkdown}).Validate())
+}
+
+func TestSearchIndexURL(t *testing.T) {
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"acme.config.ServerConfig"}), nil, Formatter{}, nil)
+	assert.NoError(t, err)
+
+	// Search index is looked up relative to the home URL, like the links
+	// between the pages.
+	c := &Config{SearchIndex: true, HomeURL: "/docs/config/overview"}
+	assert.NoError(t, c.Validate())
+	files, err := c.Renderer().Render(pages)
+	assert.NoError(t, err)
+	assert.Contains(t, string(files[0].Content), `fetch("/docs/config/search-index.json")`)
+
+	// Absolute home URL is kept as is.
+	c = &Config{SearchIndex: true, HomeURL: "https://example.com/docs/config/overview"}
+	assert.NoError(t, c.Validate())
+	files, err = c.Renderer().Render(pages)
+	assert.NoError(t, err)
+	assert.Contains(t, string(files[0].Content), `fetch("https://example.com/docs/config/search-index.json")`)
+
+	// Overridden search partial gets the URL as well.
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "search.tmpl"), []byte(`<a href="{{ searchIndexURL }}">index</a>`), 0644))
+	c = &Config{SearchIndex: true, TemplateDir: dir}
+	assert.NoError(t, c.Validate())
+	files, err = c.Renderer().Render(pages)
+	assert.NoError(t, err)
+	assert.Contains(t, string(files[0].Content), `<a href="../search-index.json">index</a>`)
+
+	// Template errors point at the template.
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "search.tmpl"), []byte(`{{ .Name`), 0644))
+	err = (&Config{SearchIndex: true, TemplateDir: dir}).Validate()
+	assert.ErrorContains(t, err, "config: error parsing template "+filepath.Join(dir, "search.tmpl"))
+}
@@ -0,0 +1,305 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ServiceDoc is the documentation of a gRPC service.
+type ServiceDoc struct {
+	// Full name of the service, e.g. acme.api.ServerService.
+	Name    string
+	Comment string
+	Methods []*MethodDoc
+}
+
+// Anchor returns the HTML anchor of the service documentation.
+func (s *ServiceDoc) Anchor() string {
+	return strings.ReplaceAll(s.Name, ".", "_")
+}
+
+// MethodDoc is the documentation of an RPC.
+type MethodDoc struct {
+	Name    string
+	Comment string
+
+	// Full name of the service the method belongs to.
+	Service string
+
+	// Full names of the request and response messages, and the links to
+	// their documentation, if documented.
+	Request     string
+	RequestURL  string
+	Response    string
+	ResponseURL string
+
+	ClientStreaming bool
+	ServerStreaming bool
+
+	// Method options, as they appear in the proto, e.g.
+	// "idempotency_level = NO_SIDE_EFFECTS" or `(acme.scope) = "admin"`.
+	Options []string
+
+	// HTTP bindings of the method, from the google.api.http option.
+	HTTP []*HTTPBinding
+}
+
+// Anchor returns the HTML anchor of the method documentation.
+func (m *MethodDoc) Anchor() string {
+	return strings.ReplaceAll(m.Service+"."+m.Name, ".", "_")
+}
+
+// Streaming returns the streaming mode of the method: "unary", "client
+// streaming", "server streaming" or "bidirectional streaming".
+func (m *MethodDoc) Streaming() string {
+	switch {
+	case m.ClientStreaming && m.ServerStreaming:
+		return "bidirectional streaming"
+	case m.ClientStreaming:
+		return "client streaming"
+	case m.ServerStreaming:
+		return "server streaming"
+	}
+	return "unary"
+}
+
+// CommentLines returns the comment lines of the method.
+func (m *MethodDoc) CommentLines() []string {
+	if m.Comment == "" {
+		return nil
+	}
+	return strings.Split(m.Comment, "\n")
+}
+
+// findServices returns the services in the registry matching the formatter's
+// service patterns, sorted by name.
+func findServices(f Formatter) []protoreflect.ServiceDescriptor {
+	if len(f.services) == 0 {
+		return nil
+	}
+
+	var sds []protoreflect.ServiceDescriptor
+	Files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		for i := 0; i < fd.Services().Len(); i++ {
+			sd := fd.Services().Get(i)
+			for _, pattern := range f.services {
+				if matchPattern(pattern, string(sd.FullName())) != -1 {
+					sds = append(sds, sd)
+					break
+				}
+			}
+		}
+		return true
+	})
+	sort.Slice(sds, func(i, j int) bool { return sds[i].FullName() < sds[j].FullName() })
+	return sds
+}
+
+// serviceMessages returns the request and response messages of the
+// services' methods. Well-known types, e.g. google.protobuf.Empty, and the
+// external and excluded messages are not documented, but linked to, if
+// possible.
+func serviceMessages(sds []protoreflect.ServiceDescriptor, f Formatter) []protoreflect.FullName {
+	var msgs []protoreflect.FullName
+	for _, sd := range sds {
+		for i := 0; i < sd.Methods().Len(); i++ {
+			md := sd.Methods().Get(i)
+			for _, msg := range []protoreflect.MessageDescriptor{md.Input(), md.Output()} {
+				name := string(msg.FullName())
+				if msg.ParentFile().Package() == "google.protobuf" || f.externalURL(name) != "" || f.isExcluded(name) {
+					continue
+				}
+				msgs = append(msgs, msg.FullName())
+			}
+		}
+	}
+	return msgs
+}
+
+// optionValue formats an option value as it appears in the proto.
+func optionValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return strconv.Itoa(int(v.Enum()))
+	case protoreflect.StringKind:
+		return strconv.Quote(v.String())
+	case protoreflect.BytesKind:
+		return strconv.Quote(string(v.Bytes()))
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		var fields []string
+		rangeFields(v.Message(), func(fd protoreflect.FieldDescriptor, v protoreflect.Value) {
+			fields = append(fields, string(fd.Name())+": "+optionValue(fd, v))
+		})
+		if len(fields) == 0 {
+			return "{}"
+		}
+		return "{ " + strings.Join(fields, " ") + " }"
+	}
+	return v.String()
+}
+
+// rangeFields calls fn for each populated field of the message, in the order
+// of the field numbers. Repeated fields are expanded into their elements.
+func rangeFields(m protoreflect.Message, fn func(protoreflect.FieldDescriptor, protoreflect.Value)) {
+	var fds []protoreflect.FieldDescriptor
+	values := map[protoreflect.FieldDescriptor]protoreflect.Value{}
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		fds = append(fds, fd)
+		values[fd] = v
+		return true
+	})
+	sort.Slice(fds, func(i, j int) bool { return fds[i].Number() < fds[j].Number() })
+
+	for _, fd := range fds {
+		v := values[fd]
+		if fd.IsList() {
+			for i := 0; i < v.List().Len(); i++ {
+				fn(fd, v.List().Get(i))
+			}
+			continue
+		}
+		fn(fd, v)
+	}
+}
+
+// methodOptions returns the options of the method, as they appear in the
+// proto. Custom options are resolved using the registry.
+func methodOptions(md protoreflect.MethodDescriptor, r optionsResolver) []string {
+	opts := resolvedOptions(md, r)
+	if opts == nil {
+		return nil
+	}
+
+	var options []string
+	rangeFields(opts, func(fd protoreflect.FieldDescriptor, v protoreflect.Value) {
+		name := string(fd.Name())
+		if fd.IsExtension() {
+			name = "(" + string(fd.FullName()) + ")"
+		}
+		options = append(options, name+" = "+optionValue(fd, v))
+	})
+	return options
+}
+
+func serviceDoc(sd protoreflect.ServiceDescriptor, f Formatter) *ServiceDoc {
+	s := &ServiceDoc{
+		Name:    string(sd.FullName()),
+		Comment: plainComment(sd),
+	}
+	r := newOptionsResolver()
+	for i := 0; i < sd.Methods().Len(); i++ {
+		md := sd.Methods().Get(i)
+		s.Methods = append(s.Methods, &MethodDoc{
+			Name:            string(md.Name()),
+			Comment:         plainComment(md),
+			Service:         s.Name,
+			Request:         string(md.Input().FullName()),
+			RequestURL:      kindToURL(string(md.Input().FullName()), f),
+			Response:        string(md.Output().FullName()),
+			ResponseURL:     kindToURL(string(md.Output().FullName()), f),
+			ClientStreaming: md.IsStreamingClient(),
+			ServerStreaming: md.IsStreamingServer(),
+			Options:         methodOptions(md, r),
+			HTTP:            httpBindings(md, r),
+		})
+	}
+	return s
+}
+
+// servicesDocs returns the documentation of the services, grouped into pages
+// as per the formatter's grouping.
+func servicesDocs(sds []protoreflect.ServiceDescriptor, f Formatter) map[string][]*ServiceDoc {
+	pages := map[string][]*ServiceDoc{}
+	for _, sd := range sds {
+		page := f.Grouping().Group(string(sd.FullName()))
+		pages[page] = append(pages[page], serviceDoc(sd, f))
+	}
+	return pages
+}
+
+// ServiceTmpl is the built-in "service" partial, executed with the
+// ServiceDoc. Methods are shown in the proto syntax, with links to the
+// request and response messages, followed by their HTTP bindings, if any.
+var ServiceTmpl = `
+<h3 id="{{ .Anchor }}">{{ .Name }} <a class="anchor" href="#{{ .Anchor }}">#</a></h3>
+{{- if .Comment }}
+<p>{{ .Comment }}</p>
+{{- end }}
+<pre class="protodoc">
+{{ range .Methods }}
+{{- range .CommentLines }}<div class="comment">// {{ . }}</div>{{ end -}}
+<span id="{{ .Anchor }}">rpc {{ .Name }}</span>({{ if .ClientStreaming }}stream {{ end }}
+{{- if .RequestURL }}<a href="{{ .RequestURL }}">{{ .Request }}</a>{{ else }}{{ .Request }}{{ end -}}
+) returns ({{ if .ServerStreaming }}stream {{ end }}
+{{- if .ResponseURL }}<a href="{{ .ResponseURL }}">{{ .Response }}</a>{{ else }}{{ .Response }}{{ end -}}
+)
+{{- if .Options }} {
+{{- range .Options }}
+  option {{ . }};
+{{- end }}
+}
+{{- else }};{{ end }}
+
+{{ end -}}
+</pre>
+{{- range .Methods }}
+{{- if .HTTP }}
+<h4 id="{{ .Anchor }}_http">{{ .Name }} over HTTP</h4>
+{{- range .HTTP }}
+<pre class="protodoc">
+{{ .Text }}
+{{- if .ExampleBody }}
+<div class="comment"># Example request:</div>{{ .Curl }}
+{{- end }}
+</pre>
+{{- end }}
+{{- end }}
+{{- end }}
+`
+
+// methodProto returns the method definition in the proto syntax.
+func methodProto(m *MethodDoc) string {
+	var b strings.Builder
+	for _, line := range m.CommentLines() {
+		b.WriteString(strings.TrimRight("// "+line, " ") + "\n")
+	}
+	req, resp := m.Request, m.Response
+	if m.ClientStreaming {
+		req = "stream " + req
+	}
+	if m.ServerStreaming {
+		resp = "stream " + resp
+	}
+	b.WriteString(fmt.Sprintf("rpc %s(%s) returns (%s)", m.Name, req, resp))
+	if len(m.Options) == 0 {
+		b.WriteString(";")
+		return b.String()
+	}
+	b.WriteString(" {\n")
+	for _, opt := range m.Options {
+		b.WriteString("  option " + opt + ";\n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateDocsServices(t *testing.T) {
+	f := Formatter{}.WithRelPath("..").WithGrouping(Grouping{Root: "acme", Depth: 1}).WithServices([]string{"acme.api.*"})
+	pages, err := GenerateDocs(nil, nil, f, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api"}, pageNames(pages))
+
+	page := pages[0]
+	assert.Equal(t, []string{
+		"acme.api.FileChunk",
+		"acme.api.GetStatusRequest",
+		"acme.api.Labels",
+		"acme.api.LogEntry",
+		"acme.api.RestartRequest",
+		"acme.api.SetLabelsRequest",
+		"acme.api.Status",
+		"acme.api.Uptime",
+	}, msgNames(page))
+
+	assert.Len(t, page.Services, 1)
+	svc := page.Services[0]
+	assert.Equal(t, "acme.api.ServerService", svc.Name)
+	assert.Equal(t, "Manages the deployed servers.", svc.Comment)
+
+	var streaming []string
+	for _, m := range svc.Methods {
+		streaming = append(streaming, m.Streaming())
+	}
+	assert.Equal(t, []string{"unary", "unary", "unary", "server streaming", "client streaming", "bidirectional streaming"}, streaming)
+
+	assert.Equal(t, &MethodDoc{
+		Name:       "Restart",
+		Comment:    "Restarts the server.",
+		Service:    "acme.api.ServerService",
+		Request:    "acme.api.RestartRequest",
+		RequestURL: "../api#acme_api_RestartRequest",
+		Response:   "google.protobuf.Empty",
+		Options: []string{
+			`(acme.api.required_scope) = "admin"`,
+			`(google.api.http) = { post: "/v1/{server=servers/*}:restart" body: "*" }`,
+		},
+		HTTP: []*HTTPBinding{
+			{
+				Method:      "POST",
+				Path:        "/v1/{server=servers/*}:restart",
+				Body:        "*",
+				PathParams:  []string{"server"},
+				ExampleBody: "{\n  \"drain\": false\n}",
+			},
+		},
+	}, svc.Methods[1])
+	assert.Equal(t, []string{
+		"idempotency_level = NO_SIDE_EFFECTS",
+		`(google.api.http) = { get: "/v1/{server=servers/*}/status" additional_bindings: { get: "/v1/status" } }`,
+	}, svc.Methods[0].Options)
+	assert.Equal(t, []string{"deprecated = true"}, svc.Methods[4].Options)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteDoc(&buf, page))
+	assert.Contains(t, buf.String(), `<h3 id="acme_api_ServerService">`)
+	assert.Contains(t, buf.String(), `<span id="acme_api_ServerService_TailLogs">rpc TailLogs</span>(<a href="../api#acme_api_GetStatusRequest">acme.api.GetStatusRequest</a>) returns (stream <a href="../api#acme_api_LogEntry">acme.api.LogEntry</a>);`)
+
+	assert.Equal(t, "// Uploads files to serve.\nrpc Upload(stream acme.api.FileChunk) returns (acme.api.Status) {\n  option deprecated = true;\n}", methodProto(svc.Methods[4]))
+
+	_, err = GenerateDocs(nil, nil, f.WithServices([]string{"acme.unknown.*"}), nil)
+	assert.Error(t, err)
+}
+
+func TestGenerateDocsServicesLinkedMessages(t *testing.T) {
+	el, err := ParseExternalLink("google.protobuf.*=https://protobuf.dev/reference/protobuf/google.protobuf/#{{.Name}}")
+	assert.NoError(t, err)
+
+	// Well-known, external and excluded messages are linked to, if possible,
+	// but not documented.
+	f := Formatter{}.WithRelPath("..").WithGrouping(Grouping{Root: "acme", Depth: 1}).WithServices([]string{"acme.api.*"})
+	f = f.WithExternalLinks([]*ExternalLink{el}).WithExcludes([]string{"acme.api.RestartRequest"})
+	pages, err := GenerateDocs(nil, nil, f, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api"}, pageNames(pages))
+	assert.NotContains(t, msgNames(pages[0]), "acme.api.RestartRequest")
+
+	restart := pages[0].Services[0].Methods[1]
+	assert.Equal(t, "Restart", restart.Name)
+	assert.Equal(t, "", restart.RequestURL)
+	assert.Equal(t, "https://protobuf.dev/reference/protobuf/google.protobuf/#Empty", restart.ResponseURL)
+}
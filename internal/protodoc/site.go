@@ -0,0 +1,304 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// SiteAssetsDir is the directory the shared static assets are written to, in
+// the site mode.
+const SiteAssetsDir = "assets"
+
+// SiteTmpl is the built-in "site" template, rendering a complete HTML
+// document for a page. It's executed with the SitePage.
+var SiteTmpl = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{ .Title }}</title>
+<link rel="stylesheet" href="{{ .AssetsURL }}/protodoc.css">
+{{- template "tabs" .Page }}
+</head>
+<body>
+{{ template "sidebar" . }}
+<main>
+<nav class="breadcrumbs">
+{{- range $i, $b := .Breadcrumbs }}
+  {{- if $i }} &rsaquo; {{ end }}
+  {{- if $b.URL }}<a href="{{ $b.URL }}">{{ $b.Title }}</a>{{ else }}<span>{{ $b.Title }}</span>{{ end }}
+{{- end -}}
+</nav>
+<h1>{{ .Title }}</h1>
+{{- if .TOC }}
+<nav class="toc">
+<h2>On this page</h2>
+<ul>
+{{- range .TOC }}
+<li><a href="{{ .URL }}">{{ .Title }}</a></li>
+{{- end }}
+</ul>
+</nav>
+{{- end }}
+{{- range .Page.Services }}
+{{ template "service" . }}
+{{- end }}
+{{- range .Page.Msgs }}
+{{ template "message_header" . }}
+{{ template "message_body" . }}
+{{- end }}
+{{- range .Page.Enums }}
+{{ template "enum" . }}
+{{- end }}
+</main>
+</body>
+</html>
+`
+
+// SidebarTmpl is the built-in "sidebar" partial, listing all the pages and
+// the services, messages and enums documented on them. It's executed with the SitePage.
+var SidebarTmpl = `<nav class="sidebar">
+{{- template "search" . }}
+<ul>
+{{- range .Nav }}
+<li{{ if .Current }} class="current"{{ end }}><a href="{{ .URL }}">{{ .Title }}</a>
+{{- if .Msgs }}
+<ul>
+{{- range .Msgs }}
+<li><a href="{{ .URL }}">{{ .Title }}</a></li>
+{{- end }}
+</ul>
+{{- end }}
+</li>
+{{- end }}
+</ul>
+</nav>`
+
+// SiteCSS is the built-in stylesheet, written to assets/protodoc.css.
+var SiteCSS = `body {
+    margin: 0;
+    display: flex;
+    font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif;
+    color: #222;
+}
+a {
+    color: #1a5fb4;
+    text-decoration: none;
+}
+.sidebar {
+    flex: 0 0 300px;
+    height: 100vh;
+    position: sticky;
+    top: 0;
+    overflow-y: auto;
+    padding: 10px;
+    border-right: 1px solid #ddd;
+    background: #fafafa;
+    font-size: 14px;
+    word-break: break-all;
+}
+.sidebar ul {
+    list-style: none;
+    padding-left: 10px;
+}
+.sidebar .current > a {
+    font-weight: bold;
+}
+main {
+    flex: 1;
+    min-width: 0;
+    padding: 0 20px;
+}
+.breadcrumbs {
+    margin-top: 15px;
+    color: #888;
+}
+.toc ul {
+    padding-left: 20px;
+}
+.comment {
+    color: #888;
+}
+.protodoc {
+    border: 1px solid #ddd;
+    border-left: 3px solid #e6522c;
+    border-radius: 0;
+    padding-left: 10px;
+    overflow-x: auto;
+}
+`
+
+// NavLink is a link in the site navigation.
+type NavLink struct {
+	Title string
+	URL   string
+}
+
+// NavPage is a page in the site navigation, along with the services,
+// messages and enums documented on it.
+type NavPage struct {
+	NavLink
+	Current bool
+	Msgs    []*NavLink
+}
+
+// SitePage is the data of the site template: a page along with the site
+// navigation.
+type SitePage struct {
+	Page  *Page
+	Title string
+
+	// URL of the shared static assets directory, relative to the page.
+	AssetsURL string
+
+	// All pages, the first one being the home page.
+	Nav         []*NavPage
+	Breadcrumbs []*NavLink
+
+	// Services, messages and enums documented on this page.
+	TOC []*NavLink
+}
+
+// sitePageURL returns the URL of the page, relative to another page.
+func sitePageURL(page *Page) string {
+	return path.Join("..", htmlPagePath(page))
+}
+
+func newSitePage(page *Page, pages []*Page) *SitePage {
+	sp := &SitePage{
+		Page:      page,
+		Title:     pageTitle(page),
+		AssetsURL: path.Join("..", SiteAssetsDir),
+	}
+
+	for _, p := range pages {
+		np := &NavPage{
+			NavLink: NavLink{Title: pageTitle(p), URL: sitePageURL(p)},
+			Current: p == page,
+		}
+		var anchors []*NavLink
+		for _, svc := range p.Services {
+			anchors = append(anchors, &NavLink{Title: svc.Name, URL: "#" + svc.Anchor()})
+		}
+		for _, msg := range p.Msgs {
+			if msg.Name != "" {
+				anchors = append(anchors, &NavLink{Title: msg.Name, URL: "#" + msg.Anchor()})
+			}
+		}
+		for _, e := range p.Enums {
+			anchors = append(anchors, &NavLink{Title: e.Name, URL: "#" + e.Anchor()})
+		}
+		for _, a := range anchors {
+			np.Msgs = append(np.Msgs, &NavLink{Title: a.Title, URL: np.URL + a.URL})
+			if p == page {
+				sp.TOC = append(sp.TOC, a)
+			}
+		}
+		sp.Nav = append(sp.Nav, np)
+	}
+
+	if home := pages[0]; home != page {
+		sp.Breadcrumbs = append(sp.Breadcrumbs, &NavLink{Title: pageTitle(home), URL: sitePageURL(home)})
+	}
+	sp.Breadcrumbs = append(sp.Breadcrumbs, &NavLink{Title: sp.Title})
+	return sp
+}
+
+// siteRenderer renders pages as complete HTML documents, one directory per
+// page, with the navigation between them and the shared static assets.
+type siteRenderer struct {
+	tmpl *template.Template
+
+	// Directory with the static assets overriding the built-in ones.
+	assetsDir string
+}
+
+// NewSiteRenderer returns a renderer for the standalone site, using the
+// given templates (built-in templates if nil). Files in assetsDir, if set,
+// are written to the assets directory along with the built-in stylesheet,
+// and can override it.
+func NewSiteRenderer(tmpl *template.Template, assetsDir string) Renderer {
+	if tmpl == nil {
+		tmpl = docTmpl
+	}
+	return &siteRenderer{tmpl: tmpl, assetsDir: assetsDir}
+}
+
+func (r *siteRenderer) assets() ([]*File, error) {
+	files := []*File{{Path: path.Join(SiteAssetsDir, "protodoc.css"), Content: []byte(SiteCSS)}}
+	if r.assetsDir == "" {
+		return files, nil
+	}
+
+	err := filepath.WalkDir(r.assetsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(r.assetsDir, p)
+		if err != nil {
+			return err
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		file := &File{Path: path.Join(SiteAssetsDir, filepath.ToSlash(rel)), Content: b}
+		if file.Path == files[0].Path {
+			files[0] = file
+		} else {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading assets: %v", err)
+	}
+	return files, nil
+}
+
+func (r *siteRenderer) Render(pages []*Page) ([]*File, error) {
+	if len(pages) == 0 {
+		return nil, nil
+	}
+
+	var files []*File
+	for _, page := range pages {
+		var buf bytes.Buffer
+		if err := r.tmpl.ExecuteTemplate(&buf, SiteTemplate, newSitePage(page, pages)); err != nil {
+			return nil, fmt.Errorf("error rendering page %s: %v", page.Name, err)
+		}
+		files = append(files, &File{Path: htmlPagePath(page), Content: buf.Bytes()})
+	}
+
+	// Top level index redirects to the home page.
+	home := htmlPagePath(pages[0])
+	files = append(files, &File{
+		Path:    "index.html",
+		Content: []byte(fmt.Sprintf("<!DOCTYPE html>\n<meta http-equiv=\"refresh\" content=\"0; url=%s\">\n<a href=\"%s\">%s</a>\n", home, home, template.HTMLEscapeString(pageTitle(pages[0])))),
+	})
+
+	assets, err := r.assets()
+	if err != nil {
+		return nil, err
+	}
+	return append(files, assets...), nil
+}
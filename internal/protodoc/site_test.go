@@ -0,0 +1,69 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestSiteRenderer(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "assets", "img"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "assets", "protodoc.css"), []byte("body {}"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "assets", "img", "logo.svg"), []byte("<svg/>"), 0644))
+
+	c := &Config{Site: true, TemplateDir: dir}
+	assert.NoError(t, c.Validate())
+	f := c.Formatter()
+
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"cloudprober.probes.ProbeDef"}), nil, f, nil)
+	assert.NoError(t, err)
+
+	files, err := c.Renderer().Render(pages)
+	assert.NoError(t, err)
+
+	contents := map[string]string{}
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+		contents[file.Path] = string(file.Content)
+	}
+	assert.Equal(t, []string{"overview/index.html", "probes/index.html", "index.html", "assets/protodoc.css", "assets/img/logo.svg"}, paths)
+	assert.Equal(t, "body {}", contents["assets/protodoc.css"])
+	assert.Contains(t, contents["index.html"], `url=overview/index.html`)
+
+	overview := contents["overview/index.html"]
+	assert.Contains(t, overview, "<!DOCTYPE html>\n<html lang=\"en\">")
+	assert.Contains(t, overview, "<title>Overview</title>")
+	assert.Contains(t, overview, `<link rel="stylesheet" href="../assets/protodoc.css">`)
+	assert.Contains(t, overview, `<nav class="breadcrumbs"><span>Overview</span></nav>`)
+	assert.NotContains(t, overview, `<nav class="toc">`)
+	assert.Contains(t, overview, `<li class="current"><a href="../overview/index.html">Overview</a>`)
+	// Field links point to the page files.
+	assert.Contains(t, overview, `<a href="../probes/index.html#cloudprober_probes_http_ProbeConf">`)
+
+	probes := contents["probes/index.html"]
+	assert.Contains(t, probes, `<nav class="breadcrumbs"><a href="../overview/index.html">Overview</a> &rsaquo; <span>probes</span></nav>`)
+	assert.Contains(t, probes, `<li><a href="#cloudprober_probes_http_Header">cloudprober.probes.http.Header</a></li>`)
+	assert.Contains(t, probes, `<li><a href="../probes/index.html#cloudprober_probes_http_Header">cloudprober.probes.http.Header</a></li>`)
+	assert.Contains(t, probes, `<h3 id="cloudprober_probes_http_Header">`)
+
+	assert.Error(t, (&Config{Site: true, Output: OutputMarkdown}).Validate())
+}
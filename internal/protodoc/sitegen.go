@@ -0,0 +1,176 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported static site generators.
+const (
+	SiteGeneratorHugo       = "hugo"
+	SiteGeneratorMkDocs     = "mkdocs"
+	SiteGeneratorDocusaurus = "docusaurus"
+)
+
+// DefaultSiteTitle is the title of the documentation section, if not
+// configured.
+const DefaultSiteTitle = "Configuration"
+
+// Files describing the documentation section to the site generators.
+const (
+	HugoSectionFile        = "_index.md"
+	MkDocsNavFile          = "mkdocs-nav.yml"
+	DocusaurusCategoryFile = "_category_.json"
+)
+
+// SiteGeneratorOutput returns the output used for the given site generator:
+// HTML pages for Hugo, and Markdown pages for MkDocs and Docusaurus.
+func SiteGeneratorOutput(gen string) (string, error) {
+	switch gen {
+	case SiteGeneratorHugo:
+		return OutputHTML, nil
+	case SiteGeneratorMkDocs, SiteGeneratorDocusaurus:
+		return OutputMarkdown, nil
+	}
+	return "", fmt.Errorf("unknown site generator: %s", gen)
+}
+
+// frontMatter is the front matter of a page. Fields not used by a site
+// generator are left empty.
+type frontMatter struct {
+	Title           string `yaml:"title"`
+	Weight          int    `yaml:"weight,omitempty"`
+	SidebarLabel    string `yaml:"sidebar_label,omitempty"`
+	SidebarPosition int    `yaml:"sidebar_position,omitempty"`
+}
+
+// siteGenRenderer wraps a renderer, adding the front matter expected by a
+// static site generator to each page, and the files describing the
+// documentation section, e.g. Hugo's _index.md.
+type siteGenRenderer struct {
+	r         Renderer
+	gen       string
+	title     string
+	navPrefix string
+}
+
+// NewSiteGeneratorRenderer returns a renderer that renders pages for the
+// given static site generator, using r to render each page. Title is the
+// title of the documentation section. Nav prefix is the path of the output
+// directory relative to the MkDocs docs_dir, prefixed to the nav paths.
+func NewSiteGeneratorRenderer(gen string, r Renderer, title, navPrefix string) (Renderer, error) {
+	if _, err := SiteGeneratorOutput(gen); err != nil {
+		return nil, err
+	}
+	if title == "" {
+		title = DefaultSiteTitle
+	}
+	// Docusaurus compiles the Markdown pages as MDX.
+	if mr, ok := r.(*markdownRenderer); ok && gen == SiteGeneratorDocusaurus {
+		mdxRenderer := *mr
+		mdxRenderer.mdx = true
+		r = &mdxRenderer
+	}
+	return &siteGenRenderer{r: r, gen: gen, title: title, navPrefix: navPrefix}, nil
+}
+
+func marshalFrontMatter(fm any) ([]byte, error) {
+	b, err := yaml.Marshal(fm)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte("---\n"), b...), "---\n\n"...), nil
+}
+
+// pageFrontMatter returns the front matter of the page. Pages are weighed in
+// the order they are generated: root pages first, followed by the package
+// pages sorted by name.
+func (r *siteGenRenderer) pageFrontMatter(page *Page, weight int) *frontMatter {
+	fm := &frontMatter{Title: pageTitle(page)}
+	switch r.gen {
+	case SiteGeneratorHugo:
+		fm.Weight = weight
+	case SiteGeneratorDocusaurus:
+		fm.SidebarLabel = fm.Title
+		fm.SidebarPosition = weight
+	}
+	return fm
+}
+
+func (r *siteGenRenderer) sectionFiles(pages []*Page, files []*File) ([]*File, error) {
+	switch r.gen {
+	case SiteGeneratorHugo:
+		b, err := marshalFrontMatter(&frontMatter{Title: r.title})
+		if err != nil {
+			return nil, err
+		}
+		return []*File{{Path: HugoSectionFile, Content: b}}, nil
+
+	case SiteGeneratorMkDocs:
+		var nav []map[string]string
+		for i, page := range pages {
+			nav = append(nav, map[string]string{pageTitle(page): path.Join(r.navPrefix, files[i].Path)})
+		}
+		b, err := yaml.Marshal(map[string]any{"nav": []map[string]any{{r.title: nav}}})
+		if err != nil {
+			return nil, err
+		}
+		return []*File{{Path: MkDocsNavFile, Content: b}}, nil
+
+	case SiteGeneratorDocusaurus:
+		b, err := json.MarshalIndent(map[string]any{"label": r.title}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return []*File{{Path: DocusaurusCategoryFile, Content: append(b, '\n')}}, nil
+	}
+	return nil, nil
+}
+
+func (r *siteGenRenderer) Render(pages []*Page) ([]*File, error) {
+	var files []*File
+	for i, page := range pages {
+		pageFiles, err := r.r.Render([]*Page{page})
+		if err != nil {
+			return nil, err
+		}
+		if len(pageFiles) != 1 {
+			return nil, fmt.Errorf("page %s: expected one file, got %d", page.Name, len(pageFiles))
+		}
+
+		fm, err := marshalFrontMatter(r.pageFrontMatter(page, i+1))
+		if err != nil {
+			return nil, fmt.Errorf("error generating front matter for page %s: %v", page.Name, err)
+		}
+		file := pageFiles[0]
+		file.Content = append(fm, bytes.TrimLeft(file.Content, "\n")...)
+		files = append(files, file)
+	}
+	if len(pages) == 0 {
+		return files, nil
+	}
+
+	sectionFiles, err := r.sectionFiles(pages, files)
+	if err != nil {
+		return nil, fmt.Errorf("error generating %s files: %v", r.gen, err)
+	}
+	return append(files, sectionFiles...), nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSiteGeneratorRenderer(t *testing.T) {
+	tests := []struct {
+		gen         string
+		title       string
+		wantPaths   []string
+		wantProbes  string
+		wantSection string
+	}{
+		{
+			gen:         SiteGeneratorHugo,
+			wantPaths:   []string{"overview/index.html", "probes/index.html", "_index.md"},
+			wantProbes:  "---\ntitle: probes\nweight: 2\n---\n\n<style>",
+			wantSection: "---\ntitle: Configuration\n---\n\n",
+		},
+		{
+			gen:         SiteGeneratorMkDocs,
+			title:       "Probes Reference",
+			wantPaths:   []string{"overview.md", "probes.md", "mkdocs-nav.yml"},
+			wantProbes:  "---\ntitle: probes\n---\n\n# probes\n",
+			wantSection: "nav:\n    - Probes Reference:\n        - Overview: overview.md\n        - probes: probes.md\n",
+		},
+		{
+			gen:         SiteGeneratorDocusaurus,
+			wantPaths:   []string{"overview.md", "probes.md", "_category_.json"},
+			wantProbes:  "---\ntitle: probes\nsidebar_label: probes\nsidebar_position: 2\n---\n\n# probes\n",
+			wantSection: "{\n  \"label\": \"Configuration\"\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.gen, func(t *testing.T) {
+			c := &Config{SiteGenerator: tt.gen, Title: tt.title}
+			assert.NoError(t, c.Validate())
+
+			pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"cloudprober.probes.ProbeDef"}), nil, c.Formatter(), nil)
+			assert.NoError(t, err)
+
+			files, err := c.Renderer().Render(pages)
+			assert.NoError(t, err)
+
+			var paths []string
+			for _, file := range files {
+				paths = append(paths, file.Path)
+			}
+			assert.Equal(t, tt.wantPaths, paths)
+			assert.Contains(t, string(files[1].Content), tt.wantProbes)
+			assert.Equal(t, tt.wantSection, string(files[2].Content))
+		})
+	}
+
+	assert.Error(t, (&Config{SiteGenerator: "jekyll"}).Validate())
+	assert.Error(t, (&Config{SiteGenerator: SiteGeneratorHugo, Output: OutputMarkdown}).Validate())
+	assert.Error(t, (&Config{SiteGenerator: SiteGeneratorHugo, Site: true}).Validate())
+}
+
+func TestMkDocsNavPrefix(t *testing.T) {
+	// Output directory is nested in the MkDocs docs_dir.
+	docsDir := t.TempDir()
+	c := &Config{SiteGenerator: SiteGeneratorMkDocs, OutDir: filepath.Join(docsDir, "reference", "config"), NavPrefix: "reference/config"}
+	assert.NoError(t, c.Validate())
+
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"cloudprober.probes.ProbeDef"}), nil, c.Formatter(), nil)
+	assert.NoError(t, err)
+	files, err := c.Renderer().Render(pages)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.MkdirAll(c.OutDir, 0755))
+	var navFile []byte
+	for _, file := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(c.OutDir, file.Path), file.Content, 0644))
+		if file.Path == MkDocsNavFile {
+			navFile = file.Content
+		}
+	}
+	assert.Equal(t, "nav:\n    - Configuration:\n        - Overview: reference/config/overview.md\n        - probes: reference/config/probes.md\n", string(navFile))
+
+	// Nav paths resolve relative to docs_dir, as MkDocs does.
+	var nav struct {
+		Nav []map[string][]map[string]string `yaml:"nav"`
+	}
+	assert.NoError(t, yaml.Unmarshal(navFile, &nav))
+	for _, entry := range nav.Nav[0]["Configuration"] {
+		for _, navPath := range entry {
+			assert.FileExists(t, filepath.Join(docsDir, filepath.FromSlash(navPath)))
+		}
+	}
+
+	for _, prefix := range []string{"/docs", "../docs", "docs/", "a/../b"} {
+		assert.Error(t, (&Config{SiteGenerator: SiteGeneratorMkDocs, NavPrefix: prefix}).Validate(), prefix)
+	}
+	assert.Error(t, (&Config{SiteGenerator: SiteGeneratorHugo, NavPrefix: "docs"}).Validate())
+}
+
+func TestDocusaurusMDXComments(t *testing.T) {
+	withTestProtos(t, map[string]string{
+		"test.proto": `
+syntax = "proto2";
+package test;
+
+// Config for {{ .Name }}, e.g. {"a": 1} or <name>.
+message Config {
+  // Mode, e.g. <fast>.
+  optional Mode mode = 1;
+}
+
+// Mode, see {docs}.
+enum Mode {
+  FAST = 1;
+}
+`,
+	})
+
+	for _, gen := range []string{SiteGeneratorDocusaurus, SiteGeneratorMkDocs} {
+		t.Run(gen, func(t *testing.T) {
+			c := &Config{SiteGenerator: gen, Grouping: GroupingConfig{By: "package"}}
+			assert.NoError(t, c.Validate())
+
+			pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"test.Config"}), nil, c.Formatter(), nil)
+			assert.NoError(t, err)
+			files, err := c.Renderer().Render(pages)
+			assert.NoError(t, err)
+
+			overview, test := string(files[0].Content), string(files[1].Content)
+			if gen == SiteGeneratorDocusaurus {
+				// Comments outside the code blocks are escaped for MDX.
+				assert.Contains(t, overview, `Config for \{\{ .Name \}\}, e.g. \{"a": 1\} or \<name\>.`)
+				assert.Contains(t, test, `Mode, see \{docs\}.`)
+			} else {
+				assert.Contains(t, overview, `Config for {{ .Name }}, e.g. {"a": 1} or <name>.`)
+				assert.Contains(t, test, `Mode, see {docs}.`)
+			}
+			// Code blocks are left as is.
+			assert.Contains(t, overview, "# Mode, e.g. <fast>.\nmode: <test.Mode>")
+		})
+	}
+}
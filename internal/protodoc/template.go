@@ -0,0 +1,195 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// Names of the templates used to render the HTML pages. Each of them can be
+// overridden by a file in the template directory, named after the template
+// with a ".tmpl" extension, e.g. "token.tmpl". Other ".tmpl" files in the
+// template directory are available as additional partials. Templates are
+// html/template templates, with the sprig functions, and searchIndexURL, the
+// URL of the search index relative to the pages (empty unless the search
+// index is generated).
+//
+// Templates get the following data, which is kept stable across releases:
+//
+//	page           Page being rendered.
+//	  .Name        Name of the page, e.g. "overview" or "probes".
+//	  .Title       Title of the page, set only for the root pages when there
+//	               are multiple roots.
+//	  .Root        Full name of the root message, set only for the root pages.
+//	  .Msgs        Messages documented on the page ([]*MsgTokens).
+//	  .Services    gRPC services documented on the page ([]*ServiceDoc).
+//	  .Enums       Enums documented on the page ([]*EnumDoc).
+//	  .Formats     Formats ("yaml", "textpb") the messages are documented in,
+//	               set only if there are more than one.
+//	style          Page being rendered.
+//	search         Page being rendered, or SitePage in the site mode. Search
+//	               box, empty unless the search index is generated.
+//	message_header MsgTokens of a documented message.
+//	  .Name        Full name of the message, empty for the root message.
+//	  .Anchor      HTML anchor of the message, e.g. "cloudprober_probes_ProbeDef".
+//	  .Comment     Leading comment of the message.
+//	  .Tokens      Lines of the message's config syntax ([]*Token).
+//	  .Formats     Tokens in each format ([]*FormatTokens), set only if there
+//	               are more than one. Each has a .Format, a .Label (e.g.
+//	               "YAML") and .Tokens.
+//	message_body   MsgTokens, as above. Config syntax of the message, as tabs
+//	               if there are multiple formats.
+//	tabs           Page being rendered. Style and script for the format tabs.
+//	service        ServiceDoc of a documented gRPC service.
+//	  .Name        Full name of the service.
+//	  .Anchor      HTML anchor of the service.
+//	  .Comment     Leading comment of the service.
+//	  .Methods     RPCs of the service ([]*MethodDoc). Each has a .Name,
+//	               .Anchor, .Comment, .CommentLines, .Request and .Response
+//	               (full names of the messages), .RequestURL and .ResponseURL,
+//	               .ClientStreaming, .ServerStreaming, .Streaming (e.g.
+//	               "server streaming"), .Options (e.g.
+//	               "deprecated = true") and .HTTP, the HTTP bindings
+//	               ([]*HTTPBinding) with .Method, .Path, .Body, .PathParams,
+//	               .QueryParams, .ExampleBody, .Text and .Curl.
+//	enum           EnumDoc of a documented enum.
+//	  .Name        Full name of the enum.
+//	  .Anchor      HTML anchor of the enum.
+//	  .Comment     Leading comment of the enum.
+//	  .Deprecated  Whether the enum is deprecated.
+//	  .Values      Values of the enum ([]*EnumValueDoc). Each has a .Name,
+//	               .Anchor, .Number, .Comment, .CommentLines and .Deprecated.
+//	token          Token, a line of the config syntax.
+//	  .Anchor      HTML anchor of the field's line, if any, e.g.
+//	               "acme_config_Handler_file_dir".
+//	  .Comment     Comment lines, each prefixed with "#".
+//	  .Prefix      Indentation.
+//	  .TextHTML    Field name, as HTML.
+//	  .Sep         Separator between the field name and its kind.
+//	  .Kind        Type of the field, e.g. "string" or a message's or enum's
+//	               full name.
+//	  .URL         Link to the documentation of the field's type, if any.
+//	  .Default     Default value of the field, if any.
+//	  .Suffix      Text after the kind, e.g. " {" or " | default: 10".
+//	  .ExtraLine   Trailing newline, empty if the line is continued.
+//
+// In the site mode, pages are rendered using the site template instead of
+// the page template, along with the partials other than page and style:
+//
+//	site           SitePage: a page along with the site navigation.
+//	  .Page        Page being rendered.
+//	  .Title       Title of the page, e.g. "Overview" or "probes".
+//	  .AssetsURL   URL of the shared static assets directory.
+//	  .Nav         All pages ([]*NavPage), the first one being the home page.
+//	               Each has a .Title, .URL, .Current (whether it's the page
+//	               being rendered) and .Msgs ([]*NavLink), links to the
+//	               services, messages and enums on the page.
+//	  .Breadcrumbs Links to the home page and this page ([]*NavLink).
+//	  .TOC         Links to the services, messages and enums on this page
+//	               ([]*NavLink).
+//	sidebar        SitePage, as above.
+//
+// NavLink has a .Title and a .URL.
+const (
+	PageTemplate          = "page"
+	StyleTemplate         = "style"
+	SearchTemplate        = "search"
+	MessageHeaderTemplate = "message_header"
+	MessageBodyTemplate   = "message_body"
+	TabsTemplate          = "tabs"
+	ServiceTemplate       = "service"
+	EnumTemplate          = "enum"
+	TokenTemplate         = "token"
+	SiteTemplate          = "site"
+	SidebarTemplate       = "sidebar"
+)
+
+var docTmpl = template.Must(newDocTemplate(""))
+
+// newDocTemplate returns the built-in templates. If the search index URL is
+// set, search partial renders the search box, looking up the index at that
+// URL.
+func newDocTemplate(searchIndexURL string) (*template.Template, error) {
+	searchTmpl := EmptySearchTmpl
+	if searchIndexURL != "" {
+		searchTmpl = SearchTmpl
+	}
+
+	tmpl := template.New("protodoc").Funcs(sprig.TxtFuncMap()).Funcs(template.FuncMap{
+		"searchIndexURL": func() string { return searchIndexURL },
+	})
+	for _, t := range []struct{ name, text string }{
+		{PageTemplate, DocTmpl},
+		{StyleTemplate, StyleTmpl},
+		{SearchTemplate, searchTmpl},
+		{MessageHeaderTemplate, MessageHeaderTmpl},
+		{MessageBodyTemplate, MessageBodyTmpl},
+		{TabsTemplate, TabsTmpl},
+		{ServiceTemplate, ServiceTmpl},
+		{EnumTemplate, EnumTmpl},
+		{TokenTemplate, TokenTmpl},
+		{SiteTemplate, SiteTmpl},
+		{SidebarTemplate, SidebarTmpl},
+	} {
+		if _, err := tmpl.New(t.name).Parse(t.text); err != nil {
+			return nil, fmt.Errorf("error parsing built-in template %s: %v", t.name, err)
+		}
+	}
+	return tmpl, nil
+}
+
+// LoadTemplates returns the built-in templates, overridden by the templates
+// in dir.
+func LoadTemplates(dir string) (*template.Template, error) {
+	return loadTemplates(dir, "")
+}
+
+// loadTemplates returns the built-in templates, with the search box if the
+// search index URL is set, overridden by the templates in dir, if any.
+func loadTemplates(dir, searchIndexURL string) (*template.Template, error) {
+	tmpl, err := newDocTemplate(searchIndexURL)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return tmpl, nil
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("error reading template dir: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading template: %v", err)
+		}
+		name := strings.TrimSuffix(filepath.Base(file), ".tmpl")
+		if _, err := tmpl.New(name).Parse(string(b)); err != nil {
+			return nil, fmt.Errorf("error parsing template %s: %v", file, err)
+		}
+	}
+	return tmpl, nil
+}
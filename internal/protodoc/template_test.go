@@ -0,0 +1,56 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"style.tmpl":          `<link rel="stylesheet" href="/css/protodoc.css">`,
+		"message_header.tmpl": `{{ if .Name }}<h4 id="{{ .Anchor }}">{{ template "short_name" .Name }}</h4>{{ end }}`,
+		"short_name.tmpl":     `{{ . | splitList "." | last }}`,
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	c := &Config{TemplateDir: dir}
+	assert.NoError(t, c.Validate())
+
+	pages, err := GenerateDocs(NewRoots([]protoreflect.FullName{"cloudprober.probes.ProbeDef"}), nil, c.Formatter(), nil)
+	assert.NoError(t, err)
+
+	files, err := c.Renderer().Render(pages)
+	assert.NoError(t, err)
+	assert.Equal(t, "probes/index.html", files[1].Path)
+
+	out := string(files[1].Content)
+	assert.Contains(t, out, `<link rel="stylesheet" href="/css/protodoc.css">`)
+	assert.NotContains(t, out, "<style>")
+	assert.Contains(t, out, `<h4 id="cloudprober_probes_AdditionalLabel">AdditionalLabel</h4>`)
+	// Token partial is not overridden.
+	assert.Contains(t, out, `<div class="comment">`)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "token.tmpl"), []byte("{{ .Kind"), 0644))
+	_, err = LoadTemplates(dir)
+	assert.Error(t, err)
+}
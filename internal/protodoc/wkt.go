@@ -0,0 +1,54 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// wktKinds describe the JSON representations of the well-known types, see
+// https://protobuf.dev/programming-guides/proto3/#json. YAML configs are
+// converted to JSON before parsing, so they use the same representations.
+// Like the int64 and uint64 fields, their wrappers are written as strings.
+var wktKinds = map[protoreflect.FullName]string{
+	"google.protobuf.Any":         `object with "@type"`,
+	"google.protobuf.Duration":    `string, e.g. "1.5s"`,
+	"google.protobuf.Timestamp":   `RFC 3339 string, e.g. "1972-01-01T10:00:20.021Z"`,
+	"google.protobuf.FieldMask":   `string, e.g. "f.fooBar,h"`,
+	"google.protobuf.Struct":      "free-form object",
+	"google.protobuf.Value":       "any value",
+	"google.protobuf.ListValue":   "array",
+	"google.protobuf.Empty":       "{}",
+	"google.protobuf.DoubleValue": "double or null",
+	"google.protobuf.FloatValue":  "float or null",
+	"google.protobuf.Int64Value":  `int64 string, e.g. "10", or null`,
+	"google.protobuf.UInt64Value": `uint64 string, e.g. "10", or null`,
+	"google.protobuf.Int32Value":  "int32 or null",
+	"google.protobuf.UInt32Value": "uint32 or null",
+	"google.protobuf.BoolValue":   "bool or null",
+	"google.protobuf.StringValue": "string or null",
+	"google.protobuf.BytesValue":  "bytes or null",
+}
+
+// wktKind returns the kind describing the representation of the well-known
+// type in the formatter's syntax, or an empty string if the message is not a
+// well-known type. In textproto, well-known types are written as the regular
+// messages, e.g. "timeout { seconds: 10 }", and are documented as such.
+func wktKind(md protoreflect.MessageDescriptor, f Formatter) string {
+	if !f.json && !f.yaml {
+		return ""
+	}
+	return wktKinds[md.FullName()]
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 Manu Garg.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodoc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestWKTKind(t *testing.T) {
+	tests := []struct {
+		md   protoreflect.MessageDescriptor
+		want string
+	}{
+		{md: (&durationpb.Duration{}).ProtoReflect().Descriptor(), want: `string, e.g. "1.5s"`},
+		{md: (&timestamppb.Timestamp{}).ProtoReflect().Descriptor(), want: `RFC 3339 string, e.g. "1972-01-01T10:00:20.021Z"`},
+		{md: (&structpb.Struct{}).ProtoReflect().Descriptor(), want: "free-form object"},
+		{md: (&wrapperspb.Int32Value{}).ProtoReflect().Descriptor(), want: "int32 or null"},
+		{md: (&wrapperspb.Int64Value{}).ProtoReflect().Descriptor(), want: `int64 string, e.g. "10", or null`},
+		{md: (&wrapperspb.UInt64Value{}).ProtoReflect().Descriptor(), want: `uint64 string, e.g. "10", or null`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.md.Name()), func(t *testing.T) {
+			assert.Equal(t, tt.want, wktKind(tt.md, Formatter{}.WithJSON(true)))
+			assert.Equal(t, tt.want, wktKind(tt.md, Formatter{}.WithYAML(true, false)))
+
+			// In textproto, well-known types are regular messages.
+			assert.Equal(t, "", wktKind(tt.md, Formatter{}))
+		})
+	}
+
+	md, err := findMessage("acme.config.Handler")
+	assert.NoError(t, err)
+	assert.Equal(t, "", wktKind(md, Formatter{}.WithJSON(true)))
+}
+
+func TestDumpMessageWKT(t *testing.T) {
+	md, err := findMessage("acme.config.ServerConfig")
+	assert.NoError(t, err)
+
+	// In YAML, well-known types are never expanded, but are documented and
+	// linked to all the same.
+	f := Formatter{}.WithYAML(true, false).WithDepth(2)
+	toks, next := DumpMessage(md, f)
+	assert.Equal(t, "timeout", toks[1].Text)
+	assert.Equal(t, `string, e.g. "1.5s"`, toks[1].Kind)
+	assert.False(t, toks[1].MessageHeader)
+	assert.Contains(t, next, protoreflect.FullName("google.protobuf.Duration"))
+
+	el, err := ParseExternalLink("google.protobuf.*=https://protobuf.dev/reference/protobuf/google.protobuf/#{{.Name}}")
+	assert.NoError(t, err)
+	toks = ProcessTokensForHTML(toks, f.WithExternalLinks([]*ExternalLink{el}))
+	assert.Equal(t, "https://protobuf.dev/reference/protobuf/google.protobuf/#Duration", toks[1].URL)
+
+	// In textproto, they are documented as the regular messages.
+	toks, next = DumpMessage(md, Formatter{}.WithDepth(2))
+	assert.Equal(t, []*Token{
+		{Comment: "# Server timeout.", Kind: "google.protobuf.Duration", Text: "timeout", MessageHeader: true, NoExtraLine: true},
+		{Prefix: "  ", Kind: "int64", Text: "seconds"},
+		{Prefix: "  ", Kind: "int32", Text: "nanos", NoExtraLine: true},
+		{Text: "}"},
+	}, toks[1:5])
+	assert.Contains(t, next, protoreflect.FullName("google.protobuf.Duration"))
+}